@@ -0,0 +1,188 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/farhan-nahid/email-service/initializers"
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/repository"
+	"github.com/farhan-nahid/email-service/routes"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/farhan-nahid/email-service/workers"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ------------------- Application ------------------- //
+
+// Options replace the services New would otherwise create from the configuration, tests
+// pass an SQLite database, a capturing transport or their own templates
+type Options struct {
+	DB        *gorm.DB
+	Transport utils.Transport
+	Templates *utils.TemplateRegistry
+	Logger    *slog.Logger
+}
+
+// App is one instance of the email service. Instances share nothing but the process, so
+// several of them can run side by side
+type App struct {
+	Config    *config.Config
+	DB        *gorm.DB
+	Transport utils.Transport
+	Templates *utils.TemplateRegistry
+	Logger    *slog.Logger
+
+	limiter *middleware.RateLimiter
+	router  *gin.Engine
+	ownsDB  bool
+}
+
+// New creates an app from the configuration, the database is not migrated, call
+// initializers.MigrateDatabase when the schema may be behind
+func New(cfg *config.Config, options Options) (*App, error) {
+	if err := models.ValidateThrottleConfig(cfg.Throttle); err != nil {
+		return nil, err
+	}
+
+	app := &App{
+		Config:    cfg,
+		DB:        options.DB,
+		Transport: options.Transport,
+		Templates: options.Templates,
+		Logger:    options.Logger,
+	}
+
+	if app.Logger == nil {
+		app.Logger = utils.NewLogger(cfg.Log)
+	}
+	if app.DB == nil {
+		db, err := initializers.OpenDatabase(cfg.Database)
+		if err != nil {
+			return nil, err
+		}
+		app.DB, app.ownsDB = db, true
+	}
+	// Secrets stored by this instance are encrypted with its own key
+	if err := models.UseEncryptionKey(app.DB, cfg.Security.EncryptionKey); err != nil {
+		return nil, err
+	}
+	if app.Templates == nil {
+		// Failures are reported by /readyz, templates are parsed again on first use
+		app.Templates = utils.NewTemplateRegistry(cfg.Templates.Dir)
+		if err := app.Templates.Load(); err != nil {
+			app.Logger.Warn("Failed to load email templates", slog.String("error", err.Error()))
+		}
+	}
+	if app.Transport == nil {
+		if cfg.Email.Transport == "mailbox" {
+			app.Logger.Warn("Emails are kept in the development mailbox at /dev/mailbox and not delivered")
+			app.Transport = utils.NewMailbox(cfg.Email.MailboxSize)
+		} else {
+			app.Transport = utils.NewSMTPPoolFromConfig(cfg.SMTP)
+		}
+	}
+	app.limiter = middleware.NewRateLimiter(cfg.RateLimit)
+
+	return app, nil
+}
+
+// Router returns the HTTP handler of the app, it is built once and can be served by
+// httptest
+func (a *App) Router() *gin.Engine {
+	if a.router != nil {
+		return a.router
+	}
+
+	deps := &middleware.Dependencies{
+		Config:      a.Config,
+		DB:          a.DB,
+		Mailer:      utils.NewMailer(a.Templates, a.Transport, a.Logger),
+		RateLimiter: a.limiter,
+		Logger:      a.Logger,
+	}
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(middleware.Inject(deps))            // Make the services of this app available to handlers
+	router.Use(middleware.RequestID())             // Propagate X-Request-ID into logs
+	router.Use(middleware.Tracing())               // Start a trace span per request
+	router.Use(middleware.RequestLogger(a.Logger)) // Log each request as structured JSON
+	router.Use(middleware.Metrics())               // Record request latency per route
+
+	router.GET("/health-check", func(c *gin.Context) { utils.SuccessResponse(c, http.StatusOK, nil, "Service is up and running") })
+	routes.HealthRoute(router)                                     // Register liveness and readiness probes
+	routes.EmailRoute(router, repository.NewEmailRepository(a.DB)) // Register email routes
+	routes.WebsiteRoute(router)                                    // Register website registry routes
+	routes.SMTPProfileRoute(router)                                // Register SMTP profile routes
+	routes.DKIMKeyRoute(router)                                    // Register DKIM key routes
+	routes.SuppressionRoute(router)                                // Register suppression list routes
+	routes.UnsubscribeRoute(router)                                // Register unsubscribe routes
+	routes.BounceRoute(router)                                     // Register bounce processing routes
+	routes.ProviderWebhookRoute(router)                            // Register ESP webhook routes
+	routes.WebhookSubscriptionRoute(router)                        // Register outgoing webhook routes
+	routes.TrackingRoute(router)                                   // Register open and click tracking routes
+	routes.APIKeyRoute(router)                                     // Register API key routes
+	routes.QuotaRoute(router)                                      // Register sending quota routes
+	routes.MetricsRoute(router)                                    // Register Prometheus metrics route
+
+	if mailbox, ok := a.Transport.(*utils.Mailbox); ok {
+		routes.DevMailboxRoute(router, mailbox) // Register the development mailbox UI and API
+	}
+
+	a.router = router
+	return router
+}
+
+// Run serves HTTP on the configured port and runs the background workers until the context
+// is cancelled, then shuts the server down gracefully
+func (a *App) Run(ctx context.Context) error {
+	// Deliver queued webhook events in the background
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+	workers.StartWebhookDispatcher(workerCtx, a.DB, a.Logger)
+
+	server := &http.Server{
+		Addr:    ":" + strconv.Itoa(a.Config.Server.Port),
+		Handler: a.Router(),
+	}
+
+	failed := make(chan error, 1)
+	go func() {
+		a.Logger.Info("Starting server", slog.String("addr", server.Addr))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			failed <- err
+		}
+	}()
+
+	select {
+	case err := <-failed:
+		return err
+	case <-ctx.Done():
+	}
+	a.Logger.Info("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// Close releases pooled SMTP connections and stops the rate limiter. The database is only
+// closed when New opened it
+func (a *App) Close() {
+	a.Transport.Close()
+	a.limiter.Close()
+
+	if a.ownsDB {
+		if sqlDB, err := a.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/farhan-nahid/email-service/initializers"
+	"github.com/farhan-nahid/email-service/migration"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"gorm.io/gorm"
+)
+
+const usage = `usage: migrate [--force] <command>
+
+commands:
+  up          apply every pending migration and seed the website registry
+  down [N]    roll back the last N migrations (default 1)
+  to VERSION  migrate up or down to VERSION, 0 rolls back everything
+  status      list migrations and when they were applied
+
+Rolling back migration 1 drops every table and needs --force.`
+
+func main() {
+	args, force := os.Args[1:], false
+	if len(args) > 0 && args[0] == "--force" {
+		args, force = args[1:], true
+	}
+	if len(args) < 1 {
+		fail(usage)
+	}
+
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		fail("Error loading configuration: ", err)
+	}
+	slog.SetDefault(utils.NewLogger(cfg.Log))
+
+	db, err := initializers.OpenDatabase(cfg.Database)
+	if err != nil {
+		fail(err)
+	}
+
+	// SQLite databases are created from the models and have no migration history
+	if cfg.Database.Driver == "sqlite" {
+		if args[0] != "up" {
+			fail("only up is supported with DB_DRIVER=sqlite")
+		}
+		if err := migration.AutoMigrate(db); err != nil {
+			fail("Migration Failed: ", err)
+		}
+		seed(db, cfg)
+		slog.Info("Migration successful")
+		return
+	}
+
+	migrator, err := migration.New(db)
+	if err != nil {
+		fail(err)
+	}
+	migrator.Force = force
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		if err = migrator.Up(ctx); err == nil {
+			seed(db, cfg)
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				fail("down expects a positive number of migrations")
+			}
+		}
+		err = migrator.Down(ctx, steps)
+	case "to":
+		if len(args) < 2 {
+			fail(usage)
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil || version < 0 {
+			fail("to expects a migration version")
+		}
+		err = migrator.To(ctx, version)
+	case "status":
+		if err := printStatus(ctx, migrator); err != nil {
+			fail(err)
+		}
+		return
+	default:
+		fail(usage)
+	}
+
+	if err != nil {
+		fail("Migration Failed: ", err)
+	}
+	slog.Info("Migration successful")
+}
+
+func printStatus(ctx context.Context, migrator *migration.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return w.Flush()
+}
+
+// seed adds the existing products to the website registry
+func seed(db *gorm.DB, cfg *config.Config) {
+	if err := migration.SeedWebsites(db, models.EmailAddress(cfg.Email.From)); err != nil {
+		fail("Seeding websites Failed: ", err)
+	}
+}
+
+func fail(message ...interface{}) {
+	fmt.Fprintln(os.Stderr, message...)
+	os.Exit(1)
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ------------------- Configuration ------------------- //
+
+// Config is the typed configuration of the service. Every field can be set through the
+// environment variable in its env tag, defaults come from the default tag and sensitive
+// values are tagged secret so they are masked when the configuration is printed
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	SMTP      SMTPConfig      `yaml:"smtp" toml:"smtp"`
+	Email     EmailConfig     `yaml:"email" toml:"email"`
+	Templates TemplateConfig  `yaml:"templates" toml:"templates"`
+	Security  SecurityConfig  `yaml:"security" toml:"security"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth"`
+	DKIM      DKIMConfig      `yaml:"dkim" toml:"dkim"`
+	Tracking  TrackingConfig  `yaml:"tracking" toml:"tracking"`
+	Providers ProviderConfig  `yaml:"providers" toml:"providers"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Quota     QuotaConfig     `yaml:"quota" toml:"quota"`
+	Throttle  ThrottleConfig  `yaml:"throttle" toml:"throttle"`
+	Log       LogConfig       `yaml:"log" toml:"log"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing"`
+}
+
+type ServerConfig struct {
+	Port          int    `yaml:"port" toml:"port" env:"PORT" default:"8080" validate:"min=1,max=65535"`
+	PublicBaseURL string `yaml:"public_base_url" toml:"public_base_url" env:"PUBLIC_BASE_URL" validate:"omitempty,url"`
+}
+
+type DatabaseConfig struct {
+	// Driver is postgres, or sqlite for tests and single binary mode
+	Driver   string `yaml:"driver" toml:"driver" env:"DB_DRIVER" default:"postgres" validate:"oneof=postgres sqlite"`
+	Host     string `yaml:"host" toml:"host" env:"DB_HOST" validate:"required_if=Driver postgres"`
+	Port     int    `yaml:"port" toml:"port" env:"DB_PORT" default:"5432" validate:"min=1,max=65535"`
+	User     string `yaml:"user" toml:"user" env:"DB_USER" validate:"required_if=Driver postgres"`
+	Password string `yaml:"password" toml:"password" env:"DB_PASS" secret:"true"`
+	Name     string `yaml:"name" toml:"name" env:"DB_NAME" validate:"required_if=Driver postgres"`
+	SSLMode  string `yaml:"ssl_mode" toml:"ssl_mode" env:"DB_SSLMODE" validate:"omitempty,oneof=disable allow prefer require verify-ca verify-full"`
+	// Path is the SQLite database file, ":memory:" keeps the database in memory
+	Path string `yaml:"path" toml:"path" env:"DB_PATH" default:"email-service.db"`
+	// MigrateOnStart applies pending migrations before the server starts
+	MigrateOnStart bool `yaml:"migrate_on_start" toml:"migrate_on_start" env:"DB_MIGRATE_ON_START"`
+}
+
+type SMTPConfig struct {
+	Host          string `yaml:"host" toml:"host" env:"SMTP_HOST"`
+	Port          int    `yaml:"port" toml:"port" env:"SMTP_PORT" default:"587" validate:"min=1,max=65535"`
+	User          string `yaml:"user" toml:"user" env:"SMTP_USER"`
+	Password      string `yaml:"password" toml:"password" env:"SMTP_PASS" secret:"true"`
+	TLSMode       string `yaml:"tls_mode" toml:"tls_mode" env:"SMTP_TLS_MODE" validate:"omitempty,oneof=NONE STARTTLS TLS"`
+	AuthMechanism string `yaml:"auth_mechanism" toml:"auth_mechanism" env:"SMTP_AUTH_MECHANISM" validate:"omitempty,oneof=NONE PLAIN LOGIN CRAM-MD5"`
+
+	PoolMaxMessages        int `yaml:"pool_max_messages" toml:"pool_max_messages" env:"SMTP_POOL_MAX_MESSAGES" default:"100" validate:"min=1"`
+	PoolMaxIdle            int `yaml:"pool_max_idle" toml:"pool_max_idle" env:"SMTP_POOL_MAX_IDLE" default:"2" validate:"min=1"`
+	PoolIdleTimeoutSeconds int `yaml:"pool_idle_timeout_seconds" toml:"pool_idle_timeout_seconds" env:"SMTP_POOL_IDLE_TIMEOUT_SECONDS" default:"30" validate:"min=1"`
+}
+
+type EmailConfig struct {
+	// From is the sender the website registry is seeded with
+	From         string `yaml:"from" toml:"from" env:"EMAIL_FROM"`
+	BounceDomain string `yaml:"bounce_domain" toml:"bounce_domain" env:"BOUNCE_DOMAIN" validate:"omitempty,fqdn"`
+	// Transport is smtp, or mailbox to keep emails in memory and browse them at /dev/mailbox
+	// during development instead of delivering them
+	Transport   string `yaml:"transport" toml:"transport" env:"EMAIL_TRANSPORT" default:"smtp" validate:"oneof=smtp mailbox"`
+	MailboxSize int    `yaml:"mailbox_size" toml:"mailbox_size" env:"DEV_MAILBOX_SIZE" default:"200" validate:"min=1"`
+}
+
+type TemplateConfig struct {
+	Dir string `yaml:"dir" toml:"dir" env:"TEMPLATE_DIR" default:"templates" validate:"required"`
+}
+
+type SecurityConfig struct {
+	EncryptionKey string `yaml:"encryption_key" toml:"encryption_key" env:"ENCRYPTION_KEY" secret:"true" validate:"omitempty,base64"`
+	TokenSecret   string `yaml:"token_secret" toml:"token_secret" env:"TOKEN_SECRET" secret:"true"`
+}
+
+type AuthConfig struct {
+	AdminAPIKey       string `yaml:"admin_api_key" toml:"admin_api_key" env:"ADMIN_API_KEY" secret:"true"`
+	JWKSURL           string `yaml:"jwks_url" toml:"jwks_url" env:"JWT_JWKS_URL" validate:"omitempty,url"`
+	JWTStaticKey      string `yaml:"jwt_static_key" toml:"jwt_static_key" env:"JWT_STATIC_KEY" secret:"true"`
+	JWTIssuer         string `yaml:"jwt_issuer" toml:"jwt_issuer" env:"JWT_ISSUER" validate:"required_with=JWKSURL"`
+	JWTAudience       string `yaml:"jwt_audience" toml:"jwt_audience" env:"JWT_AUDIENCE" validate:"required_with=JWKSURL"`
+	JWTRolesClaim     string `yaml:"jwt_roles_claim" toml:"jwt_roles_claim" env:"JWT_ROLES_CLAIM" default:"roles"`
+	JWTCompaniesClaim string `yaml:"jwt_companies_claim" toml:"jwt_companies_claim" env:"JWT_COMPANIES_CLAIM" default:"companies"`
+}
+
+type DKIMConfig struct {
+	Domain         string `yaml:"domain" toml:"domain" env:"DKIM_DOMAIN" validate:"omitempty,fqdn"`
+	Selector       string `yaml:"selector" toml:"selector" env:"DKIM_SELECTOR" validate:"required_with=Domain"`
+	PrivateKeyFile string `yaml:"private_key_file" toml:"private_key_file" env:"DKIM_PRIVATE_KEY_FILE" validate:"required_with=Domain"`
+}
+
+type TrackingConfig struct {
+	ClickAllowedHosts []string `yaml:"click_allowed_hosts" toml:"click_allowed_hosts" env:"CLICK_TRACKING_ALLOWED_HOSTS"`
+}
+
+type ProviderConfig struct {
+	SESTopicARN       string `yaml:"ses_topic_arn" toml:"ses_topic_arn" env:"SES_SNS_TOPIC_ARN"`
+	SendGridPublicKey string `yaml:"sendgrid_public_key" toml:"sendgrid_public_key" env:"SENDGRID_WEBHOOK_PUBLIC_KEY"`
+	MailgunSigningKey string `yaml:"mailgun_signing_key" toml:"mailgun_signing_key" env:"MAILGUN_WEBHOOK_SIGNING_KEY" secret:"true"`
+	PostmarkUser      string `yaml:"postmark_user" toml:"postmark_user" env:"POSTMARK_WEBHOOK_USER"`
+	PostmarkPassword  string `yaml:"postmark_password" toml:"postmark_password" env:"POSTMARK_WEBHOOK_PASS" secret:"true"`
+}
+
+type RateLimitConfig struct {
+	// RPS of zero disables API rate limiting, Burst defaults to twice the rate
+	RPS   float64 `yaml:"rps" toml:"rps" env:"API_RATE_LIMIT_RPS" default:"20" validate:"gte=0"`
+	Burst int     `yaml:"burst" toml:"burst" env:"API_RATE_LIMIT_BURST" validate:"gte=0"`
+}
+
+// QuotaLimits are emails per calendar window, zero means unlimited. The env tags are
+// suffixes of the QUOTA_COMPANY and QUOTA_WEBSITE prefixes
+type QuotaLimits struct {
+	Hourly  int `yaml:"hourly" toml:"hourly" env:"HOURLY" validate:"gte=0"`
+	Daily   int `yaml:"daily" toml:"daily" env:"DAILY" validate:"gte=0"`
+	Monthly int `yaml:"monthly" toml:"monthly" env:"MONTHLY" validate:"gte=0"`
+}
+
+type QuotaConfig struct {
+	Company QuotaLimits `yaml:"company" toml:"company" env:"QUOTA_COMPANY"`
+	Website QuotaLimits `yaml:"website" toml:"website" env:"QUOTA_WEBSITE"`
+}
+
+type ThrottleConfig struct {
+	DuplicateWindow time.Duration `yaml:"duplicate_window" toml:"duplicate_window" env:"THROTTLE_DUPLICATE_WINDOW" validate:"gte=0"`
+	// DuplicateWindows overrides the window per source, e.g. "RESET_PASSWORD=1m,CHANGE_EMAIL=5m"
+	DuplicateWindows    DurationMap `yaml:"duplicate_windows" toml:"duplicate_windows" env:"THROTTLE_DUPLICATE_WINDOWS" validate:"dive,gte=0"`
+	RecipientDailyLimit int         `yaml:"recipient_daily_limit" toml:"recipient_daily_limit" env:"THROTTLE_RECIPIENT_DAILY_LIMIT" validate:"gte=0"`
+}
+
+// DurationMap holds durations by name, written as "NAME=duration" pairs separated by commas
+type DurationMap map[string]time.Duration
+
+func (m *DurationMap) UnmarshalText(text []byte) error {
+	parsed := DurationMap{}
+	for _, entry := range strings.Split(string(text), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, value, found := strings.Cut(entry, "=")
+		if name = strings.TrimSpace(name); !found || name == "" {
+			return fmt.Errorf("entry %q must be NAME=duration", entry)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("entry %q: %w", entry, err)
+		}
+		parsed[name] = duration
+	}
+	*m = parsed
+	return nil
+}
+
+func (m DurationMap) MarshalText() ([]byte, error) {
+	entries := make([]string, 0, len(m))
+	for name, duration := range m {
+		entries = append(entries, name+"="+duration.String())
+	}
+	sort.Strings(entries)
+	return []byte(strings.Join(entries, ",")), nil
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level" env:"LOG_LEVEL" default:"info" validate:"oneof=debug info warn error DEBUG INFO WARN ERROR"`
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT" default:"json" validate:"oneof=json text"`
+}
+
+type TracingConfig struct {
+	Exporter    string `yaml:"exporter" toml:"exporter" env:"OTEL_TRACES_EXPORTER" default:"none" validate:"oneof=none otlp stdout"`
+	ServiceName string `yaml:"service_name" toml:"service_name" env:"OTEL_SERVICE_NAME" default:"email-service"`
+	Endpoint    string `yaml:"endpoint" toml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" validate:"omitempty,url"`
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottleDuplicateWindows(t *testing.T) {
+	tests := []struct {
+		raw     string
+		windows DurationMap
+		err     string
+	}{
+		{"RESET_PASSWORD=1m, CHANGE_EMAIL=90s", DurationMap{"RESET_PASSWORD": time.Minute, "CHANGE_EMAIL": 90 * time.Second}, ""},
+		{"RESET_PASSWORD=1m,", DurationMap{"RESET_PASSWORD": time.Minute}, ""},
+		{"RESET_PASSWORD=1 minute", nil, "invalid THROTTLE_DUPLICATE_WINDOWS"},
+		{"RESET_PASSWORD", nil, "must be NAME=duration"},
+		{"=1m", nil, "must be NAME=duration"},
+		{"RESET_PASSWORD=-1m", nil, "THROTTLE_DUPLICATE_WINDOWS[RESET_PASSWORD] must be at least 0"},
+	}
+
+	for _, test := range tests {
+		t.Setenv("DB_DRIVER", "sqlite")
+		t.Setenv("THROTTLE_DUPLICATE_WINDOWS", test.raw)
+
+		cfg, err := Load("")
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%q: expected an error containing %q, got %v", test.raw, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.raw, err)
+			continue
+		}
+		if len(cfg.Throttle.DuplicateWindows) != len(test.windows) {
+			t.Errorf("%q: expected %v, got %v", test.raw, test.windows, cfg.Throttle.DuplicateWindows)
+		}
+		for name, window := range test.windows {
+			if cfg.Throttle.DuplicateWindows[name] != window {
+				t.Errorf("%q: expected %s=%s, got %v", test.raw, name, window, cfg.Throttle.DuplicateWindows)
+			}
+		}
+	}
+}
+
+func TestThrottleDuplicateWindowsFromFile(t *testing.T) {
+	t.Setenv("DB_DRIVER", "sqlite")
+
+	cfg, err := Load("../config.example.yaml")
+	if err != nil {
+		t.Fatalf("loading the example config: %v", err)
+	}
+	if cfg.Throttle.DuplicateWindows["RESET_PASSWORD"] != time.Minute {
+		t.Fatalf("expected the RESET_PASSWORD window from the file, got %v", cfg.Throttle.DuplicateWindows)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+		return path
+	}
+	yamlPath := writeFile("config.yaml", "server:\n  port: 9090\ndatabase:\n  driver: sqlite\n  path: from-file.db\nsmtp:\n  port: 2525\n")
+	tomlPath := writeFile("config.toml", "[server]\nport = 9191\n[database]\ndriver = \"sqlite\"\n")
+
+	tests := []struct {
+		name   string
+		path   string
+		env    map[string]string
+		port   int
+		dbPath string
+		smtp   int
+	}{
+		{"defaults", "", map[string]string{"DB_DRIVER": "sqlite"}, 8080, "email-service.db", 587},
+		{"yaml file over defaults", yamlPath, nil, 9090, "from-file.db", 2525},
+		{"toml file over defaults", tomlPath, nil, 9191, "email-service.db", 587},
+		{"environment over file", yamlPath, map[string]string{"PORT": "7070", "SMTP_PORT": " "}, 7070, "from-file.db", 2525},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := Load(test.path)
+			if err != nil {
+				t.Fatalf("loading: %v", err)
+			}
+			if cfg.Server.Port != test.port || cfg.Database.Path != test.dbPath || cfg.SMTP.Port != test.smtp {
+				t.Fatalf("expected port %d, db path %s and smtp port %d, got %d, %s and %d",
+					test.port, test.dbPath, test.smtp, cfg.Server.Port, cfg.Database.Path, cfg.SMTP.Port)
+			}
+		})
+	}
+
+	if _, err := Load(filepath.Join(dir, "config.json")); err == nil {
+		t.Error("expected unsupported config file types to be rejected")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		err  string
+	}{
+		{"postgres needs a host", map[string]string{"DB_DRIVER": "postgres", "DB_USER": "app", "DB_NAME": "email"}, "DB_HOST is required when DB_DRIVER is postgres"},
+		{"unknown driver", map[string]string{"DB_DRIVER": "mysql"}, "DB_DRIVER must be one of postgres, sqlite"},
+		{"port out of range", map[string]string{"PORT": "70000"}, "PORT must be at most 65535"},
+		{"port is not a number", map[string]string{"PORT": "http"}, `invalid PORT "http"`},
+		{"bad duration", map[string]string{"THROTTLE_DUPLICATE_WINDOW": "ten minutes"}, `invalid THROTTLE_DUPLICATE_WINDOW "ten minutes"`},
+		{"nested quota limits", map[string]string{"QUOTA_COMPANY_HOURLY": "-1"}, "QUOTA_COMPANY_HOURLY must be at least 0"},
+		{"jwks needs an issuer", map[string]string{"JWT_JWKS_URL": "https://id.example.com/jwks", "JWT_AUDIENCE": "email-service"}, "JWT_ISSUER is required when JWT_JWKS_URL is set"},
+		{"jwks needs an audience", map[string]string{"JWT_JWKS_URL": "https://id.example.com/jwks", "JWT_ISSUER": "https://id.example.com"}, "JWT_AUDIENCE is required when JWT_JWKS_URL is set"},
+		{"dkim domain needs a selector", map[string]string{"DKIM_DOMAIN": "example.com", "DKIM_PRIVATE_KEY_FILE": "dkim.pem"}, "DKIM_SELECTOR is required when DKIM_DOMAIN is set"},
+		{"encryption key is not base64", map[string]string{"ENCRYPTION_KEY": "not base64!"}, "ENCRYPTION_KEY must be base64 encoded"},
+		{"encryption key is too short", map[string]string{"ENCRYPTION_KEY": "c2hvcnQ="}, "ENCRYPTION_KEY must be 32 bytes encoded as base64"},
+		{"several problems are reported together", map[string]string{"LOG_FORMAT": "xml", "EMAIL_TRANSPORT": "carrier-pigeon"}, "EMAIL_TRANSPORT must be one of smtp, mailbox; LOG_FORMAT must be one of json, text"},
+		{"valid", map[string]string{"JWT_JWKS_URL": "https://id.example.com/jwks", "JWT_ISSUER": "https://id.example.com", "JWT_AUDIENCE": "email-service"}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("DB_DRIVER", "sqlite")
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := Load("")
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("expected the configuration to be valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected an error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("DB_PASS", "database-password")
+	t.Setenv("ADMIN_API_KEY", "admin-key")
+	t.Setenv("SMTP_PASS", "smtp-password")
+	t.Setenv("THROTTLE_DUPLICATE_WINDOW", "90s")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("loading: %v", err)
+	}
+	redacted := cfg.Redacted()
+
+	encoded, err := json.Marshal(redacted)
+	if err != nil {
+		t.Fatalf("encoding: %v", err)
+	}
+	for _, secret := range []string{"database-password", "admin-key", "smtp-password"} {
+		if strings.Contains(string(encoded), secret) {
+			t.Errorf("%s is not masked: %s", secret, encoded)
+		}
+	}
+
+	section := func(name string) map[string]interface{} {
+		return redacted[name].(map[string]interface{})
+	}
+	tests := []struct {
+		section, key string
+		value        interface{}
+	}{
+		{"database", "password", "********"},
+		{"auth", "admin_api_key", "********"},
+		{"auth", "jwt_static_key", ""},
+		{"security", "encryption_key", ""},
+		{"database", "driver", "sqlite"},
+		{"server", "port", 8080},
+		{"throttle", "duplicate_window", "1m30s"},
+	}
+	for _, test := range tests {
+		if value := section(test.section)[test.key]; value != test.value {
+			t.Errorf("%s.%s: expected %v, got %v", test.section, test.key, test.value, value)
+		}
+	}
+}
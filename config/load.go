@@ -0,0 +1,254 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-playground/validator/v10"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds the configuration from, in increasing precedence, the defaults, the optional
+// YAML or TOML file at path, an optional .env file and the environment and validates it.
+// The result is passed to the app, nothing reads it globally
+func Load(path string) (*Config, error) {
+	cfg, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func load(path string) (*Config, error) {
+	cfg := &Config{}
+	if err := walk(reflect.ValueOf(cfg).Elem(), "", applyDefault); err != nil {
+		return cfg, err
+	}
+
+	if path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return cfg, err
+		}
+	}
+
+	// Containers pass real environment variables, a missing .env file is not an error.
+	// godotenv never overrides variables that are already set
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return cfg, fmt.Errorf("reading .env: %w", err)
+	}
+
+	err := walk(reflect.ValueOf(cfg).Elem(), "", applyEnv)
+	return cfg, err
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks the configuration, errors name the environment variable to set
+func (c *Config) Validate() error {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		if name := field.Tag.Get("env"); name != "" {
+			return name
+		}
+		return field.Name
+	})
+
+	var problems []string
+	if err := validate.Struct(c); err != nil {
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			return err
+		}
+		for _, fieldError := range validationErrors {
+			problems = append(problems, describe(fieldError))
+		}
+	}
+
+	if c.Security.EncryptionKey != "" {
+		if key, err := decodeBase64(c.Security.EncryptionKey); err != nil || len(key) != 32 {
+			problems = append(problems, "ENCRYPTION_KEY must be 32 bytes encoded as base64")
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func describe(fieldError validator.FieldError) string {
+	name := fieldError.Field()
+	if fieldError.Namespace() != "" && strings.Count(fieldError.Namespace(), ".") > 2 {
+		// Nested quota limits are named after their prefix, e.g. QUOTA_COMPANY_HOURLY
+		parts := strings.Split(fieldError.Namespace(), ".")
+		name = parts[len(parts)-2] + "_" + name
+	}
+
+	switch fieldError.Tag() {
+	case "required":
+		return name + " is required"
+	case "required_if":
+		condition := strings.Fields(fieldError.Param())
+		if len(condition) == 2 {
+			return name + " is required when " + siblingEnv(fieldError, condition[0]) + " is " + condition[1]
+		}
+		return name + " is required"
+	case "required_with":
+		return name + " is required when " + siblingEnv(fieldError, fieldError.Param()) + " is set"
+	case "oneof":
+		return name + " must be one of " + strings.ReplaceAll(fieldError.Param(), " ", ", ")
+	case "min", "gte":
+		return name + " must be at least " + fieldError.Param()
+	case "max":
+		return name + " must be at most " + fieldError.Param()
+	case "url":
+		return name + " must be a URL"
+	case "fqdn":
+		return name + " must be a domain name"
+	case "base64":
+		return name + " must be base64 encoded"
+	}
+	return name + " is not valid"
+}
+
+// siblingEnv returns the environment variable of the named field, which lives in the same
+// struct as the field that failed
+func siblingEnv(fieldError validator.FieldError, sibling string) string {
+	parts := strings.Split(fieldError.StructNamespace(), ".")
+	parent := reflect.TypeOf(Config{})
+	for _, part := range parts[1 : len(parts)-1] {
+		field, ok := parent.FieldByName(part)
+		if !ok {
+			return sibling
+		}
+		parent = field.Type
+	}
+
+	if field, ok := parent.FieldByName(sibling); ok && field.Tag.Get("env") != "" {
+		return field.Tag.Get("env")
+	}
+	return sibling
+}
+
+// ------------------- Reflection ------------------- //
+
+// walk calls apply for every leaf field with its full environment variable name, struct
+// fields with an env tag prefix the names of their own fields
+func walk(value reflect.Value, prefix string, apply func(reflect.Value, reflect.StructField, string) error) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := field.Tag.Get("env")
+		if name != "" && prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Duration(0)) {
+			if err := walk(value.Field(i), name, apply); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := apply(value.Field(i), field, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyDefault(value reflect.Value, field reflect.StructField, name string) error {
+	if fallback, ok := field.Tag.Lookup("default"); ok {
+		return setField(value, name, fallback)
+	}
+	return nil
+}
+
+// applyEnv overrides the field with its environment variable, empty variables such as the
+// blank entries of .env.example are ignored
+func applyEnv(value reflect.Value, field reflect.StructField, name string) error {
+	if raw := strings.TrimSpace(os.Getenv(name)); name != "" && raw != "" {
+		return setField(value, name, raw)
+	}
+	return nil
+}
+
+func setField(value reflect.Value, name, raw string) error {
+	invalid := func(err error) error {
+		return fmt.Errorf("invalid %s %q: %w", name, raw, err)
+	}
+
+	switch {
+	case value.Addr().Type().Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()):
+		if err := value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)); err != nil {
+			return invalid(err)
+		}
+	case value.Type() == reflect.TypeOf(time.Duration(0)):
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return invalid(err)
+		}
+		value.SetInt(int64(duration))
+	case value.Kind() == reflect.String:
+		value.SetString(raw)
+	case value.Kind() == reflect.Int:
+		number, err := strconv.Atoi(raw)
+		if err != nil {
+			return invalid(err)
+		}
+		value.SetInt(int64(number))
+	case value.Kind() == reflect.Float64:
+		number, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		value.SetFloat(number)
+	case value.Kind() == reflect.Bool:
+		flag, err := strconv.ParseBool(raw)
+		if err != nil {
+			return invalid(err)
+		}
+		value.SetBool(flag)
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported config type %s for %s", value.Type(), name)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Redacted returns the effective configuration keyed by the file keys with secrets masked,
+// it is logged at startup
+func (c *Config) Redacted() map[string]interface{} {
+	return redact(reflect.ValueOf(c).Elem())
+}
+
+func redact(value reflect.Value) map[string]interface{} {
+	result := make(map[string]interface{}, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		fieldValue := value.Field(i)
+
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			result[key] = redact(fieldValue)
+		case field.Tag.Get("secret") == "true":
+			result[key] = ""
+			if !fieldValue.IsZero() {
+				result[key] = "********"
+			}
+		case field.Type == reflect.TypeOf(time.Duration(0)):
+			result[key] = time.Duration(fieldValue.Int()).String()
+		default:
+			result[key] = fieldValue.Interface()
+		}
+	}
+	return result
+}
+
+func decodeBase64(value string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(value)
+}
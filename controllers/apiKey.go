@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// currentPrincipal returns the caller stored by the Authenticate middleware
+func currentPrincipal(c *gin.Context) *models.Principal {
+	if principal, ok := c.Get("principal"); ok {
+		if p, ok := principal.(*models.Principal); ok {
+			return p
+		}
+	}
+	return &models.Principal{}
+}
+
+func CreateAPIKey(c *gin.Context) {
+	validatedData, exists := c.Get("validatedData")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("data not found in context"))
+		return
+	}
+
+	keyData, ok := validatedData.(models.APIKey)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid data format"))
+		return
+	}
+
+	key, prefix, err := utils.GenerateAPIKey()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	newKey := models.APIKey{
+		Name:        keyData.Name,
+		Prefix:      prefix,
+		Hash:        utils.HashAPIKey(key),
+		Scopes:      keyData.Scopes,
+		CompanyUUID: keyData.CompanyUUID,
+		Admin:       keyData.Admin,
+		ExpiresAt:   keyData.ExpiresAt,
+	}
+
+	if err := requestDB(c).Create(&newKey).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// The key is only ever returned here
+	utils.SuccessResponse(c, http.StatusCreated, gin.H{"api_key": newKey, "key": key}, "API key created successfully")
+}
+
+func GetAPIKeys(c *gin.Context) {
+	var keys []models.APIKey
+
+	if err := requestDB(c).Order("id").Find(&keys).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, keys, "API keys retrieved successfully")
+}
+
+func DeleteAPIKeyByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid ID format in request URL"))
+		return
+	}
+
+	result := requestDB(c).Delete(&models.APIKey{}, id)
+	if result.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, errors.New("API key not found"))
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "API key deleted successfully")
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// IngestDSN accepts a raw RFC 3464 delivery status notification and marks the original
+// email as bounced when delivery permanently failed
+func IngestDSN(c *gin.Context) {
+	dsn, err := utils.ParseDSN(c.Request.Body)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Match by Message-ID first, the VERP return path covers servers that drop the original headers
+	var email models.Email
+	var query *gorm.DB
+	switch {
+	case dsn.OriginalMessageID != "":
+		query = requestDB(c).Where("message_id = ?", dsn.OriginalMessageID)
+	case dsn.VERPEmailUUID != "":
+		query = requestDB(c).Where("uuid = ?", dsn.VERPEmailUUID)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("original message could not be identified"))
+		return
+	}
+
+	if err := query.First(&email).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, errors.New("email not found"))
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	for _, recipient := range dsn.Recipients {
+		if !recipient.IsHardBounce() {
+			continue
+		}
+
+		reason := recipient.DiagnosticCode
+		if reason == "" {
+			reason = recipient.Status
+		}
+
+		// Marking the email as bounced also suppresses the recipient
+		event := models.EmailEvent{Type: models.EventBounced, Provider: "dsn", Recipient: models.EmailAddress(recipient.FinalRecipient), Reason: reason}
+		if err := models.ApplyEvent(requestDB(c), &email, event); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+			return
+		}
+		break
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, email, "Delivery status notification processed successfully")
+}
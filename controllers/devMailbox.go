@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// DevMailboxHandler serves the messages captured by the development mailbox as a web UI and
+// a JSON API
+type DevMailboxHandler struct {
+	Mailbox *utils.Mailbox
+}
+
+// NewDevMailboxHandler creates the mailbox handlers
+func NewDevMailboxHandler(mailbox *utils.Mailbox) *DevMailboxHandler {
+	return &DevMailboxHandler{Mailbox: mailbox}
+}
+
+// mailboxPage lists the captured messages and shows the selected one. The HTML body is
+// loaded into a sandboxed frame so its styles and scripts cannot affect the page
+var mailboxPage = template.Must(template.New("mailbox").Funcs(template.FuncMap{
+	"sortedHeaders": sortedHeaders,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charset="UTF-8" />
+    <meta name="viewport" content="width=device-width, initial-scale=1.0" />
+    <title>Mailbox</title>
+    <style>
+      body { margin: 0; font-family: system-ui, sans-serif; font-size: 14px; display: flex; height: 100vh; }
+      nav { width: 340px; border-right: 1px solid #ddd; overflow-y: auto; }
+      nav header { display: flex; justify-content: space-between; align-items: center; padding: 12px; border-bottom: 1px solid #ddd; }
+      nav a.message { display: block; padding: 10px 12px; border-bottom: 1px solid #eee; color: inherit; text-decoration: none; }
+      nav a.message.selected { background: #eef4ff; }
+      nav small, main small { color: #666; }
+      main { flex: 1; overflow-y: auto; padding: 16px 24px; }
+      iframe { width: 100%; height: 480px; border: 1px solid #ddd; }
+      pre { white-space: pre-wrap; background: #f7f7f7; padding: 12px; }
+      table { border-collapse: collapse; }
+      td { padding: 2px 12px 2px 0; vertical-align: top; }
+    </style>
+  </head>
+  <body>
+    <nav>
+      <header>
+        <strong>Mailbox ({{ len .Messages }})</strong>
+        <span>
+          <a href="/dev/mailbox">Refresh</a>
+          <button type="button" onclick="fetch('/dev/mailbox/messages', {method: 'DELETE'}).then(() => location.assign('/dev/mailbox'))">Clear</button>
+        </span>
+      </header>
+      {{ range .Messages }}
+      <a class="message{{ if and $.Selected (eq .ID $.Selected.ID) }} selected{{ end }}" href="/dev/mailbox?id={{ .ID }}">
+        <div><strong>{{ .Subject }}</strong></div>
+        <div>{{ .To }}</div>
+        <small>{{ .ReceivedAt.Format "2006-01-02 15:04:05" }}</small>
+      </a>
+      {{ else }}
+      <p style="padding: 12px">No emails captured yet.</p>
+      {{ end }}
+    </nav>
+    <main>
+      {{ with .Selected }}
+      <h2>{{ .Subject }}</h2>
+      <p>
+        From {{ .From }} to {{ .To }}<br />
+        <small>Received {{ .ReceivedAt.Format "2006-01-02 15:04:05" }}{{ if .SMTPHost }} for {{ .SMTPHost }}{{ end }} &middot; <a href="/dev/mailbox/messages/{{ .ID }}/raw">Raw message</a> &middot; <a href="/dev/mailbox/messages/{{ .ID }}">JSON</a></small>
+      </p>
+      {{ if .HTML }}
+      <h3>HTML</h3>
+      <iframe sandbox src="/dev/mailbox/messages/{{ .ID }}/html" title="HTML body"></iframe>
+      {{ end }}
+      {{ if .Text }}
+      <h3>Text</h3>
+      <pre>{{ .Text }}</pre>
+      {{ end }}
+      {{ if .Attachments }}
+      <h3>Attachments</h3>
+      <ul>
+        {{ $id := .ID }}
+        {{ range $index, $attachment := .Attachments }}
+        <li><a href="/dev/mailbox/messages/{{ $id }}/attachments/{{ $index }}">{{ or $attachment.Filename "unnamed" }}</a> <small>{{ $attachment.ContentType }}, {{ $attachment.Size }} bytes</small></li>
+        {{ end }}
+      </ul>
+      {{ end }}
+      <h3>Headers</h3>
+      <table>
+        {{ range sortedHeaders .Headers }}
+        <tr><td><strong>{{ .Name }}</strong></td><td>{{ .Value }}</td></tr>
+        {{ end }}
+      </table>
+      {{ end }}
+    </main>
+  </body>
+</html>
+`))
+
+type mailboxHeader struct {
+	Name  string
+	Value string
+}
+
+// sortedHeaders lists the headers by name so the page is stable between reloads
+func sortedHeaders(headers map[string][]string) []mailboxHeader {
+	var sorted []mailboxHeader
+	for name, values := range headers {
+		for _, value := range values {
+			sorted = append(sorted, mailboxHeader{Name: name, Value: value})
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+// ShowMailbox renders the UI, the message in the id query parameter or the newest one is
+// selected
+func (h *DevMailboxHandler) ShowMailbox(c *gin.Context) {
+	messages := h.Mailbox.Messages()
+
+	var selected *utils.MailboxMessage
+	if id := c.Query("id"); id != "" {
+		selected, _ = h.Mailbox.Message(id)
+	} else if len(messages) > 0 {
+		selected = messages[0]
+	}
+
+	var body bytes.Buffer
+	if err := mailboxPage.Execute(&body, gin.H{"Messages": messages, "Selected": selected}); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body.Bytes())
+}
+
+func (h *DevMailboxHandler) GetMailboxMessages(c *gin.Context) {
+	utils.SuccessResponse(c, http.StatusOK, h.Mailbox.Messages(), "Messages retrieved successfully")
+}
+
+func (h *DevMailboxHandler) GetMailboxMessage(c *gin.Context) {
+	message, ok := h.findMessage(c)
+	if !ok {
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, message, "Message retrieved successfully")
+}
+
+// GetMailboxMessageHTML serves the rendered HTML body, the sandbox policy keeps scripts of
+// the email from running when it is opened directly
+func (h *DevMailboxHandler) GetMailboxMessageHTML(c *gin.Context) {
+	message, ok := h.findMessage(c)
+	if !ok {
+		return
+	}
+	c.Header("Content-Security-Policy", "sandbox")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(message.HTML))
+}
+
+// GetMailboxMessageRaw serves the message as it would have been sent, mail clients open it as
+// an .eml file
+func (h *DevMailboxHandler) GetMailboxMessageRaw(c *gin.Context) {
+	message, ok := h.findMessage(c)
+	if !ok {
+		return
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": message.ID + ".eml"}))
+	c.Data(http.StatusOK, "message/rfc822", message.Raw)
+}
+
+func (h *DevMailboxHandler) GetMailboxAttachment(c *gin.Context) {
+	message, ok := h.findMessage(c)
+	if !ok {
+		return
+	}
+
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 || index >= len(message.Attachments) {
+		utils.ErrorResponse(c, http.StatusNotFound, errors.New("attachment not found"))
+		return
+	}
+
+	attachment := message.Attachments[index]
+	if attachment.Filename != "" {
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	}
+	c.Data(http.StatusOK, attachment.ContentType, attachment.Content)
+}
+
+func (h *DevMailboxHandler) ClearMailbox(c *gin.Context) {
+	h.Mailbox.Clear()
+	utils.SuccessResponse(c, http.StatusOK, nil, "Mailbox cleared successfully")
+}
+
+func (h *DevMailboxHandler) findMessage(c *gin.Context) (*utils.MailboxMessage, bool) {
+	message, ok := h.Mailbox.Message(c.Param("id"))
+	if !ok {
+		utils.ErrorResponse(c, http.StatusNotFound, errors.New("message not found"))
+	}
+	return message, ok
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// resolveDKIMKey finds the signing key for the sender domain, keys stored in the database
+// take precedence over the one configured through the environment
+func resolveDKIMKey(db *gorm.DB, defaults config.DKIMConfig, sender models.EmailAddress) (*utils.DKIMKey, error) {
+	address, err := mail.ParseAddress(string(sender))
+	if err != nil {
+		return nil, err
+	}
+	domain := strings.ToLower(address.Address[strings.LastIndex(address.Address, "@")+1:])
+
+	var key models.DKIMKey
+	err = db.Where("domain = ?", domain).First(&key).Error
+	if err == nil {
+		return key.Key(), nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return utils.DKIMKeyFromConfig(defaults, domain)
+}
+
+func CreateDKIMKey(c *gin.Context) {
+	validatedData, exists := c.Get("validatedData")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("data not found in context"))
+		return
+	}
+
+	keyData, ok := validatedData.(models.DKIMKey)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid data format"))
+		return
+	}
+
+	if _, err := utils.ParseDKIMPrivateKey(string(keyData.PrivateKey)); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Replace the key of an existing domain so keys can be rotated
+	key := models.DKIMKey{Domain: strings.ToLower(keyData.Domain)}
+	if err := requestDB(c).
+		Where("domain = ?", key.Domain).
+		Assign(models.DKIMKey{Selector: keyData.Selector, PrivateKey: keyData.PrivateKey}).
+		FirstOrCreate(&key).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+	key.DNSRecord, _ = utils.DKIMDNSRecord(string(key.PrivateKey))
+
+	utils.SuccessResponse(c, http.StatusCreated, key, "DKIM key saved successfully")
+}
+
+func GetDKIMKeys(c *gin.Context) {
+	var keys []models.DKIMKey
+
+	if err := requestDB(c).Order("domain").Find(&keys).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, keys, "DKIM keys retrieved successfully")
+}
+
+func DeleteDKIMKeyByDomain(c *gin.Context) {
+	// Deleted for good, a soft deleted row would keep holding the domain in the unique index
+	result := requestDB(c).Unscoped().Where("domain = ?", strings.ToLower(c.Param("domain"))).Delete(&models.DKIMKey{})
+	if result.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, errors.New("dkim key not found"))
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "DKIM key deleted successfully")
+}
@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/mail"
+	"time"
 
-	"github.com/farhan-nahid/email-service/initializers"
+	"github.com/farhan-nahid/email-service/middleware"
 	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/repository"
 	"github.com/farhan-nahid/email-service/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -14,6 +17,12 @@ import (
 	"gorm.io/gorm"
 )
 
+// requestDB returns the database handle bound to the request context, queries are traced as
+// part of the request
+func requestDB(c *gin.Context) *gorm.DB {
+	return middleware.Deps(c).DB.WithContext(c.Request.Context())
+}
+
 // checks the given string is a valid UUID
 func isValidUUID(str string) bool {
 	// Attempt to parse the string as a UUID
@@ -21,7 +30,36 @@ func isValidUUID(str string) bool {
 	return err == nil
 }
 
-func CreateEmail(c *gin.Context) {
+// EmailHandler serves the email endpoints, emails are stored through the injected repository
+type EmailHandler struct {
+	Emails repository.EmailRepository
+}
+
+// NewEmailHandler creates the email handlers for the repository
+func NewEmailHandler(emails repository.EmailRepository) *EmailHandler {
+	return &EmailHandler{Emails: emails}
+}
+
+// emailDetail is the email detail response including engagement aggregates
+type emailDetail struct {
+	models.Email
+	Tracking models.TrackingStats `json:"tracking"`
+}
+
+// rejectEmail records an email that was not sent and tells the caller why
+func (h *EmailHandler) rejectEmail(c *gin.Context, email *models.Email, status models.Status, reason string) {
+	email.Status = status
+	email.StatusReason = reason
+
+	if err := h.Emails.Create(c.Request.Context(), email); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, email, "Email not sent: "+reason)
+}
+
+func (h *EmailHandler) CreateEmail(c *gin.Context) {
 	validatedData, exists := c.Get("validatedData")
 	if !exists {
 		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("data not found in context"))
@@ -34,12 +72,42 @@ func CreateEmail(c *gin.Context) {
 		return
 	}
 
+	// Callers can only send emails for their own company
+	if !currentPrincipal(c).CanAccessCompany(emailData.CompanyUUID) {
+		utils.ErrorResponse(c, http.StatusForbidden, errors.New("company is not accessible with these credentials"))
+		return
+	}
+
 	var payload interface{}
 
 	// Parse (unmarshal) the JSON string into the struct
 	err := json.Unmarshal([]byte(emailData.Payload), &payload)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid payload format"))
+		return
+	}
+
+	// Look up the website in the registry
+	var website models.WebsiteConfig
+	if err := requestDB(c).Where("code = ?", emailData.Website).First(&website).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusBadRequest, errors.New("unknown website"))
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if !website.AllowsSource(emailData.Source) {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("source is not allowed for this website"))
+		return
+	}
+
+	utils.EmailsCreated.WithLabelValues(string(emailData.Website), string(emailData.Source)).Inc()
+
+	// Fall back to the website default sender
+	if emailData.Sender == "" {
+		emailData.Sender = website.DefaultSender
 	}
 
 	// Create a new email instance using the validated data
@@ -54,32 +122,126 @@ func CreateEmail(c *gin.Context) {
 		Payload:     emailData.Payload,
 		Status:      "SENT",
 	}
+	newEmail.AssignMessageID()
+
+	// Skip recipients on the suppression list
+	suppression, err := models.ActiveSuppression(requestDB(c), emailData.Recipient, emailData.Website, emailData.CompanyUUID)
+	if err == nil {
+		h.rejectEmail(c, &newEmail, models.Suppressed, "recipient is suppressed: "+string(suppression.Reason))
+		return
+	}
+	if err != gorm.ErrRecordNotFound {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Respect unsubscribes for non transactional sources
+	var unsubscribeLink string
+	if category := emailData.Source.Category(); category != "" {
+		unsubscribed, err := models.IsUnsubscribed(requestDB(c), emailData.Recipient, emailData.Website, category)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+			return
+		}
+		if unsubscribed {
+			h.rejectEmail(c, &newEmail, models.Suppressed, "recipient unsubscribed from "+category+" emails")
+			return
+		}
+
+		if unsubscribeLink, err = unsubscribeURL(middleware.Deps(c).Config, emailData.Recipient, emailData.Website, category); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	// Block accidental duplicates and floods to a single recipient
+	reason, err := models.CheckRecipientThrottle(requestDB(c), middleware.Deps(c).Config.Throttle, &newEmail, time.Now())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+	if reason != "" {
+		h.rejectEmail(c, &newEmail, models.Throttled, reason)
+		return
+	}
+
+	// Stop runaway senders before anything reaches the SMTP server
+	if !enforceSendingQuota(c, &newEmail) {
+		return
+	}
+
+	// Resolve the SMTP profile for the company and website, the development mailbox works
+	// without an SMTP server
+	smtpConfig, err := resolveSMTPConfig(requestDB(c), middleware.Deps(c).Config.SMTP, emailData.CompanyUUID, website)
+	if err != nil && !middleware.Deps(c).Mailer.Captures() {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
 
-	var sender string;
+	// Resolve the DKIM key for the sender domain
+	dkimKey, err := resolveDKIMKey(requestDB(c), middleware.Deps(c).Config.DKIM, emailData.Sender)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
 
-	if emailData.Website == "AK" {
-		sender = "Attendance Keeper <" + string(emailData.Sender) + ">"
-	} else if emailData.Website == "MYE" {
-		sender = "Manage Your Ecommerce <" + string(emailData.Sender) + ">"
-	} else {
-		sender = "Inventory Keeper <" + string(emailData.Sender) + ">"
+	// Open and click tracking are enabled per website and can be overridden per request
+	trackOpens := website.TrackOpens
+	if emailData.TrackOpens != nil {
+		trackOpens = *emailData.TrackOpens
+	}
+
+	trackClicks := website.TrackClicks
+	if emailData.TrackClicks != nil {
+		trackClicks = *emailData.TrackClicks
+	}
+
+	var pixelURL string
+	var trackClick func(link string) (string, bool)
+	if trackOpens {
+		if pixelURL, err = utils.OpenPixelURL(middleware.Deps(c).Config, newEmail.UUID.String()); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if trackClicks {
+		trackClick = func(link string) (string, bool) {
+			return utils.ClickTrackingURL(middleware.Deps(c).Config, newEmail.UUID.String(), link)
+		}
 	}
 
+	sender := (&mail.Address{Name: website.DisplayName, Address: string(emailData.Sender)}).String()
 
 	// Send Email
-	err = utils.SendEmail(utils.Data{
+	err = middleware.Deps(c).Mailer.SendEmail(c.Request.Context(), utils.Data{
 		Name: emailData.Name,
 		Sender: sender,
+		ReplyTo: string(website.ReplyTo),
 		Receiver: string(emailData.Recipient),
 		Subject: emailData.Subject,
 		Payload: payload,
-	}, "/templates/" + string(emailData.Website) + "/" + string(emailData.Source) + ".html")
+		Branding: utils.Branding{
+			DisplayName:  website.DisplayName,
+			LogoURL:      website.LogoURL,
+			PrimaryColor: website.PrimaryColor,
+			FooterText:   website.FooterText,
+		},
+		SMTP: smtpConfig,
+		DKIM: dkimKey,
+		UnsubscribeURL: unsubscribeLink,
+		MessageID: newEmail.MessageID,
+		ReturnPath: utils.ReturnPath(middleware.Deps(c).Config.Email.BounceDomain, newEmail.UUID.String()),
+		Headers: providerHeaders(newEmail.UUID.String()),
+		OpenPixelURL: pixelURL,
+		TrackClick: trackClick,
+	}, string(emailData.Website) + "/" + string(emailData.Source))
 
 	
 	if err !=  nil{
 		newEmail.Status = "FAILED"
+		newEmail.StatusReason = err.Error()
 		// Save the email to the database
-		if err := initializers.DB.Create(&newEmail).Error; err != nil {
+		if err := h.Emails.Create(c.Request.Context(), &newEmail); err != nil {
 			// If an error occurs while saving the email, return an error utils
 			utils.ErrorResponse(c, http.StatusInternalServerError, err)
 			return
@@ -89,7 +251,7 @@ func CreateEmail(c *gin.Context) {
 	}
 
 	// Save the email to the database
-	if err := initializers.DB.Create(&newEmail).Error; err != nil {
+	if err := h.Emails.Create(c.Request.Context(), &newEmail); err != nil {
 		// If an error occurs while saving the email, return an error utils
 		utils.ErrorResponse(c, http.StatusInternalServerError, err)
 		return
@@ -100,11 +262,10 @@ func CreateEmail(c *gin.Context) {
 }
 
 
-func GetEmails(c *gin.Context) {
-	var emails []models.Email
-
+func (h *EmailHandler) GetEmails(c *gin.Context) {
 	// Retrieve emails from the database
-	if err := initializers.DB.Find(&emails).Error; err != nil {
+	emails, err := h.Emails.List(c.Request.Context(), currentPrincipal(c))
+	if err != nil {
 		// If there's an error querying the database, return a 500 utils
 		utils.ErrorResponse(c, http.StatusInternalServerError, nil)
 		return
@@ -121,11 +282,10 @@ func GetEmails(c *gin.Context) {
 }
 
 
-func GetDeletedEmails(c *gin.Context) {
-	var emails []models.Email
-
+func (h *EmailHandler) GetDeletedEmails(c *gin.Context) {
 	// Retrieve deleted emails from the database
-	if err := initializers.DB.Unscoped().Where("deleted_at IS NOT NULL").Find(&emails).Error; err != nil {
+	emails, err := h.Emails.ListDeleted(c.Request.Context(), currentPrincipal(c))
+	if err != nil {
 		// If there's an error querying the database, return a 500 utils
 		utils.ErrorResponse(c, http.StatusInternalServerError, nil)
 		return
@@ -142,20 +302,16 @@ func GetDeletedEmails(c *gin.Context) {
 }
 
 
-func GetEmailByUUID(c *gin.Context) {
+func (h *EmailHandler) GetEmailByUUID(c *gin.Context) {
 	// Validate the UUID	
 	if !isValidUUID(c.Param("uuid")) {
 		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid UUID format in request URL"))
 		return
 	}
 
-	// Declare a variable of type Email to hold the result
-	var email models.Email
-
-	
-	
 	// Get the email by UUID from the database
-	if err := initializers.DB.Where("uuid = ?", c.Param("uuid")).First(&email).Error; err != nil {
+	email, err := h.Emails.FindByUUID(c.Request.Context(), currentPrincipal(c), c.Param("uuid"), false)
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			// If the email is not found, return a 404 utils
 			utils.ErrorResponse(c, http.StatusNotFound, nil)
@@ -166,12 +322,19 @@ func GetEmailByUUID(c *gin.Context) {
 		return
 	}
 
+	// Attach the open tracking aggregates
+	stats, err := models.GetTrackingStats(requestDB(c), email.UUID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
 	// Return a success utils
-	utils.SuccessResponse(c, http.StatusOK, email, "Email retrieved successfully")
+	utils.SuccessResponse(c, http.StatusOK, emailDetail{Email: email, Tracking: stats}, "Email retrieved successfully")
 }
 
 
-func UpdateEmailByUUID(c *gin.Context) {
+func (h *EmailHandler) UpdateEmailByUUID(c *gin.Context) {
 	// Validate the UUID	
 	if !isValidUUID(c.Param("uuid")) {
 		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid UUID format in request URL"))
@@ -190,8 +353,8 @@ func UpdateEmailByUUID(c *gin.Context) {
 	}
 
 	// Find the existing email record
-	var email models.Email
-	if err := initializers.DB.Where("uuid = ?", c.Param("uuid")).First(&email).Error; err != nil {
+	email, err := h.Emails.FindByUUID(c.Request.Context(), currentPrincipal(c), c.Param("uuid"), false)
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, errors.New("email not found"))
 		return
 	}
@@ -202,6 +365,10 @@ func UpdateEmailByUUID(c *gin.Context) {
 
 	// Update only the provided fields
 	if updateData.CompanyUUID != uuid.Nil {
+		if !currentPrincipal(c).CanAccessCompany(updateData.CompanyUUID) {
+			utils.ErrorResponse(c, http.StatusForbidden, errors.New("company is not accessible with these credentials"))
+			return
+		}
 		email.CompanyUUID = updateData.CompanyUUID
 	}
 
@@ -233,6 +400,12 @@ func UpdateEmailByUUID(c *gin.Context) {
 			utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid status value"))
 			return
 		}
+		// Bounces and complaints create suppressions shared by every company, only admins
+		// may record them by hand
+		if updateData.Status.IsProviderReported() && !currentPrincipal(c).Admin {
+			utils.ErrorResponse(c, http.StatusForbidden, errors.New("status "+string(updateData.Status)+" can only be set by provider events"))
+			return
+		}
 		email.Status = updateData.Status
 	}
 
@@ -259,7 +432,7 @@ func UpdateEmailByUUID(c *gin.Context) {
 	}
 
 	// Save the updated email
-	if err := initializers.DB.Save(&email).Error; err != nil {
+	if err := h.Emails.Save(c.Request.Context(), &email); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err)
 		return
 	}
@@ -269,18 +442,16 @@ func UpdateEmailByUUID(c *gin.Context) {
 }
 
 
-func DeleteEmailByUUID(c *gin.Context) {
+func (h *EmailHandler) DeleteEmailByUUID(c *gin.Context) {
 	// Validate the UUID format
 	if !isValidUUID(c.Param("uuid")) {
 		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid UUID format in request URL"))
 		return
 	}
 
-	// Declare a variable of type Email to hold the result
-	var email models.Email
-
 	// Attempt to find the email by UUID in the database
-	if err := initializers.DB.Where("uuid = ?", c.Param("uuid")).First(&email).Error; err != nil {
+	email, err := h.Emails.FindByUUID(c.Request.Context(), currentPrincipal(c), c.Param("uuid"), false)
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			// If the email is not found, return a 404 utils
 			utils.ErrorResponse(c, http.StatusNotFound, errors.New("email not found"))
@@ -298,7 +469,7 @@ func DeleteEmailByUUID(c *gin.Context) {
 	}
 
 	// Delete the email from the database
-	if err := initializers.DB.Delete(&email).Error; err != nil {
+	if err := h.Emails.Delete(c.Request.Context(), &email); err != nil {
 		// If an error occurs while deleting, return an error utils
 		utils.ErrorResponse(c, http.StatusInternalServerError, err)
 		return
@@ -307,3 +478,34 @@ func DeleteEmailByUUID(c *gin.Context) {
 	// Return a success utils after deletion
 	utils.SuccessResponse(c, http.StatusOK, nil, "Email deleted successfully")
 }
+
+func (h *EmailHandler) RestoreEmailByUUID(c *gin.Context) {
+	// Validate the UUID format
+	if !isValidUUID(c.Param("uuid")) {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid UUID format in request URL"))
+		return
+	}
+
+	// Deleted emails are only found when soft delete scoping is disabled
+	email, err := h.Emails.FindByUUID(c.Request.Context(), currentPrincipal(c), c.Param("uuid"), true)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, errors.New("email not found"))
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if !email.DeletedAt.Valid {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("email is not deleted"))
+		return
+	}
+
+	if err := h.Emails.Restore(c.Request.Context(), &email); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, email, "Email restored successfully")
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout bounds each readiness check so a hanging dependency fails the probe
+// instead of blocking it
+const readinessTimeout = 3 * time.Second
+
+// componentStatus is the result of one readiness check
+type componentStatus struct {
+	Status    string      `json:"status"`
+	LatencyMS int64       `json:"latency_ms"`
+	Error     string      `json:"error,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+type readinessCheck func(ctx context.Context, deps *middleware.Dependencies) (interface{}, error)
+
+// Livez only reports that the process is serving requests, it never checks dependencies so
+// an outage of Postgres or SMTP does not get the service restarted
+func Livez(c *gin.Context) {
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"status": "ok"}, "Service is alive")
+}
+
+// Readyz checks every dependency concurrently and returns 503 when any of them is down
+func Readyz(c *gin.Context) {
+	checks := map[string]readinessCheck{
+		"database":  checkDatabase,
+		"templates": checkTemplates,
+		"queue":     checkWebhookQueue,
+		"smtp":      checkSMTP,
+	}
+
+	deps := middleware.Deps(c)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	components := make(map[string]componentStatus, len(checks))
+	ready := true
+
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+			defer cancel()
+
+			start := time.Now()
+			details, err := check(ctx, deps)
+			status := componentStatus{Status: "ok", LatencyMS: time.Since(start).Milliseconds(), Details: details}
+			if err != nil {
+				status.Status = "fail"
+				status.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			components[name] = status
+			ready = ready && err == nil
+		}()
+	}
+	wg.Wait()
+
+	if !ready {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"data":    components,
+			"success": false,
+			"message": "Service is not ready",
+		})
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, components, "Service is ready")
+}
+
+func checkDatabase(ctx context.Context, deps *middleware.Dependencies) (interface{}, error) {
+	sqlDB, err := deps.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	return nil, sqlDB.PingContext(ctx)
+}
+
+func checkTemplates(ctx context.Context, deps *middleware.Dependencies) (interface{}, error) {
+	status := deps.Mailer.Templates.Status()
+	if status.Error != "" {
+		return status, errors.New(status.Error)
+	}
+	return status, nil
+}
+
+// checkWebhookQueue makes sure the delivery queue table can be read, it reports the backlog
+func checkWebhookQueue(ctx context.Context, deps *middleware.Dependencies) (interface{}, error) {
+	var pending int64
+	err := deps.DB.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("state = ?", models.DeliveryPending).
+		Count(&pending).Error
+	return gin.H{"pending": pending}, err
+}
+
+// checkSMTP pings the default server, stored profiles are reported by GetSMTPProfileStatus
+// so one unused profile does not take every replica out of the load balancer. The error is
+// reduced to a reason as the probe is unauthenticated
+func checkSMTP(ctx context.Context, deps *middleware.Dependencies) (interface{}, error) {
+	if deps.Mailer.Captures() {
+		return gin.H{"transport": "mailbox"}, nil
+	}
+
+	config, err := utils.DefaultSMTPConfig(deps.Config.SMTP)
+	if err != nil {
+		return nil, errors.New("no smtp server configured")
+	}
+	if err := utils.PingSMTP(ctx, config); err != nil {
+		deps.Logger.WarnContext(ctx, "SMTP readiness check failed", slog.Any("error", err))
+		return nil, errors.New("smtp server is unreachable: " + utils.SMTPFailureReason(err))
+	}
+	return nil, nil
+}
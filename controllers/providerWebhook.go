@@ -0,0 +1,451 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// providerEvent is an event normalized from a provider payload together with
+// the identifiers used to find the email it belongs to
+type providerEvent struct {
+	MessageID string
+	EmailUUID string
+	Event     models.EmailEvent
+}
+
+// providerParser verifies a provider request and normalizes its events
+type providerParser func(c *gin.Context, body []byte) ([]providerEvent, error)
+
+var providerParsers = map[string]providerParser{
+	"ses":      parseSESEvents,
+	"sendgrid": parseSendGridEvents,
+	"mailgun":  parseMailgunEvents,
+	"postmark": parsePostmarkEvents,
+}
+
+// IngestProviderWebhook accepts delivery, bounce, complaint and open events from an ESP
+func IngestProviderWebhook(c *gin.Context) {
+	provider := c.Param("provider")
+	parse, ok := providerParsers[provider]
+	if !ok {
+		utils.ErrorResponse(c, http.StatusNotFound, errors.New("unknown provider"))
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 5<<20))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	events, err := parse(c, body)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidSignature) {
+			utils.ErrorResponse(c, http.StatusUnauthorized, err)
+		} else {
+			utils.ErrorResponse(c, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	processed := 0
+	for _, event := range events {
+		email, err := findEventEmail(requestDB(c), event)
+		if err == gorm.ErrRecordNotFound {
+			// Events for mail not sent by this service are ignored
+			continue
+		}
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		event.Event.Provider = provider
+		if err := models.ApplyEvent(requestDB(c), email, event.Event); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+			return
+		}
+		processed++
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"received": len(events), "processed": processed}, "Webhook processed successfully")
+}
+
+// providerHeaders tags outgoing mail with the email UUID in the formats the providers echo
+// back in their webhooks, relays strip their own headers before delivery
+func providerHeaders(emailUUID string) map[string]string {
+	return map[string]string{
+		"X-Email-UUID":             emailUUID,
+		"X-SMTPAPI":                `{"unique_args":{"email_uuid":"` + emailUUID + `"}}`,
+		"X-Mailgun-Variables":      `{"email_uuid":"` + emailUUID + `"}`,
+		"X-PM-Metadata-email_uuid": emailUUID,
+	}
+}
+
+// findEventEmail matches an event by Message-ID, falling back to the email UUID header
+func findEventEmail(db *gorm.DB, event providerEvent) (*models.Email, error) {
+	var email models.Email
+
+	if event.MessageID != "" {
+		messageID := "<" + strings.Trim(strings.TrimSpace(event.MessageID), "<>") + ">"
+		err := db.Where("message_id = ?", messageID).First(&email).Error
+		if err == nil || err != gorm.ErrRecordNotFound {
+			return &email, err
+		}
+	}
+
+	if isValidUUID(event.EmailUUID) {
+		err := db.Where("uuid = ?", event.EmailUUID).First(&email).Error
+		return &email, err
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+// ------------------- Amazon SES via SNS ------------------- //
+
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	EventType        string `json:"eventType"`
+	Mail             struct {
+		MessageID     string `json:"messageId"`
+		CommonHeaders struct {
+			MessageID string `json:"messageId"`
+		} `json:"commonHeaders"`
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+	} `json:"mail"`
+	Bounce struct {
+		BounceType        string    `json:"bounceType"`
+		Timestamp         time.Time `json:"timestamp"`
+		BouncedRecipients []struct {
+			EmailAddress   string `json:"emailAddress"`
+			DiagnosticCode string `json:"diagnosticCode"`
+		} `json:"bouncedRecipients"`
+	} `json:"bounce"`
+	Complaint struct {
+		Timestamp             time.Time `json:"timestamp"`
+		ComplaintFeedbackType string    `json:"complaintFeedbackType"`
+		ComplainedRecipients  []struct {
+			EmailAddress string `json:"emailAddress"`
+		} `json:"complainedRecipients"`
+	} `json:"complaint"`
+	Delivery struct {
+		Timestamp  time.Time `json:"timestamp"`
+		Recipients []string  `json:"recipients"`
+	} `json:"delivery"`
+	Open struct {
+		Timestamp time.Time `json:"timestamp"`
+		UserAgent string    `json:"userAgent"`
+	} `json:"open"`
+}
+
+func parseSESEvents(c *gin.Context, body []byte) ([]providerEvent, error) {
+	var message utils.SNSMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		return nil, err
+	}
+
+	// Any AWS account can sign messages for its own topic, only the configured topic is
+	// trusted and nothing is accepted or confirmed until SES_SNS_TOPIC_ARN is set
+	if topic := middleware.Deps(c).Config.Providers.SESTopicARN; topic == "" || topic != message.TopicArn {
+		return nil, utils.ErrInvalidSignature
+	}
+
+	if err := utils.VerifySNSMessage(&message); err != nil {
+		return nil, err
+	}
+
+	// Confirm the subscription so SNS starts delivering notifications
+	if message.Type == "SubscriptionConfirmation" {
+		if !utils.IsSNSURL(message.SubscribeURL) {
+			return nil, utils.ErrInvalidSignature
+		}
+		response, err := (&http.Client{Timeout: 10 * time.Second}).Get(message.SubscribeURL)
+		if err != nil {
+			return nil, err
+		}
+		response.Body.Close()
+		return nil, nil
+	}
+	if message.Type != "Notification" {
+		return nil, nil
+	}
+
+	var notification sesNotification
+	if err := json.Unmarshal([]byte(message.Message), &notification); err != nil {
+		return nil, err
+	}
+
+	base := providerEvent{MessageID: notification.Mail.CommonHeaders.MessageID}
+	for _, header := range notification.Mail.Headers {
+		switch strings.ToLower(header.Name) {
+		case "message-id":
+			base.MessageID = header.Value
+		case "x-email-uuid":
+			base.EmailUUID = header.Value
+		}
+	}
+
+	kind := notification.EventType
+	if kind == "" {
+		kind = notification.NotificationType
+	}
+
+	var events []providerEvent
+	switch kind {
+	case "Bounce":
+		eventType := models.EventBounced
+		if notification.Bounce.BounceType != "Permanent" {
+			eventType = models.EventDeferred
+		}
+		for _, recipient := range notification.Bounce.BouncedRecipients {
+			event := base
+			event.Event = models.EmailEvent{Type: eventType, Recipient: models.EmailAddress(recipient.EmailAddress), Reason: recipient.DiagnosticCode, OccurredAt: notification.Bounce.Timestamp}
+			events = append(events, event)
+		}
+	case "Complaint":
+		for _, recipient := range notification.Complaint.ComplainedRecipients {
+			event := base
+			event.Event = models.EmailEvent{Type: models.EventComplained, Recipient: models.EmailAddress(recipient.EmailAddress), Reason: notification.Complaint.ComplaintFeedbackType, OccurredAt: notification.Complaint.Timestamp}
+			events = append(events, event)
+		}
+	case "Delivery":
+		for _, recipient := range notification.Delivery.Recipients {
+			event := base
+			event.Event = models.EmailEvent{Type: models.EventDelivered, Recipient: models.EmailAddress(recipient), OccurredAt: notification.Delivery.Timestamp}
+			events = append(events, event)
+		}
+	case "Open":
+		event := base
+		event.Event = models.EmailEvent{Type: models.EventOpened, OccurredAt: notification.Open.Timestamp}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// ------------------- SendGrid ------------------- //
+
+type sendGridEvent struct {
+	Email     string `json:"email"`
+	Timestamp int64  `json:"timestamp"`
+	Event     string `json:"event"`
+	SMTPID    string `json:"smtp-id"`
+	Reason    string `json:"reason"`
+	Type      string `json:"type"`
+	EmailUUID string `json:"email_uuid"`
+}
+
+func parseSendGridEvents(c *gin.Context, body []byte) ([]providerEvent, error) {
+	err := utils.VerifySendGridSignature(
+		middleware.Deps(c).Config.Providers.SendGridPublicKey,
+		c.GetHeader("X-Twilio-Email-Event-Webhook-Signature"),
+		c.GetHeader("X-Twilio-Email-Event-Webhook-Timestamp"),
+		body,
+	)
+	if err != nil {
+		return nil, errors.Join(utils.ErrInvalidSignature, err)
+	}
+
+	var payload []sendGridEvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	var events []providerEvent
+	for _, item := range payload {
+		var eventType models.EventType
+		switch item.Event {
+		case "delivered":
+			eventType = models.EventDelivered
+		case "bounce":
+			// Blocks are temporary rejections, only real bounces suppress the recipient
+			eventType = models.EventBounced
+			if item.Type == "blocked" {
+				eventType = models.EventDeferred
+			}
+		case "deferred":
+			eventType = models.EventDeferred
+		case "spamreport":
+			eventType = models.EventComplained
+		case "open":
+			eventType = models.EventOpened
+		case "click":
+			eventType = models.EventClicked
+		default:
+			continue
+		}
+
+		events = append(events, providerEvent{
+			MessageID: item.SMTPID,
+			EmailUUID: item.EmailUUID,
+			Event: models.EmailEvent{
+				Type:       eventType,
+				Recipient:  models.EmailAddress(item.Email),
+				Reason:     item.Reason,
+				OccurredAt: time.Unix(item.Timestamp, 0),
+			},
+		})
+	}
+
+	return events, nil
+}
+
+// ------------------- Mailgun ------------------- //
+
+type mailgunWebhook struct {
+	Signature struct {
+		Timestamp string `json:"timestamp"`
+		Token     string `json:"token"`
+		Signature string `json:"signature"`
+	} `json:"signature"`
+	EventData struct {
+		Event     string  `json:"event"`
+		Severity  string  `json:"severity"`
+		Recipient string  `json:"recipient"`
+		Timestamp float64 `json:"timestamp"`
+		Reason    string  `json:"reason"`
+		Message   struct {
+			Headers struct {
+				MessageID string `json:"message-id"`
+			} `json:"headers"`
+		} `json:"message"`
+		DeliveryStatus struct {
+			Description string `json:"description"`
+			Message     string `json:"message"`
+		} `json:"delivery-status"`
+		UserVariables map[string]interface{} `json:"user-variables"`
+	} `json:"event-data"`
+}
+
+func parseMailgunEvents(c *gin.Context, body []byte) ([]providerEvent, error) {
+	var payload mailgunWebhook
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	signature := payload.Signature
+	if err := utils.VerifyMailgunSignature(middleware.Deps(c).Config.Providers.MailgunSigningKey, signature.Timestamp, signature.Token, signature.Signature); err != nil {
+		return nil, err
+	}
+
+	// The timestamp check alone lets a captured payload be replayed for a few minutes
+	claimed, err := models.ClaimWebhookToken(requestDB(c), "mailgun", signature.Token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, utils.ErrInvalidSignature
+	}
+
+	data := payload.EventData
+	var eventType models.EventType
+	switch data.Event {
+	case "delivered":
+		eventType = models.EventDelivered
+	case "failed":
+		eventType = models.EventDeferred
+		if data.Severity == "permanent" {
+			eventType = models.EventBounced
+		}
+	case "complained":
+		eventType = models.EventComplained
+	case "opened":
+		eventType = models.EventOpened
+	case "clicked":
+		eventType = models.EventClicked
+	default:
+		return nil, nil
+	}
+
+	reason := data.DeliveryStatus.Description
+	if reason == "" {
+		reason = data.DeliveryStatus.Message
+	}
+	if reason == "" {
+		reason = data.Reason
+	}
+
+	emailUUID, _ := data.UserVariables["email_uuid"].(string)
+	seconds := int64(data.Timestamp)
+	return []providerEvent{{
+		MessageID: data.Message.Headers.MessageID,
+		EmailUUID: emailUUID,
+		Event: models.EmailEvent{
+			Type:       eventType,
+			Recipient:  models.EmailAddress(data.Recipient),
+			Reason:     reason,
+			OccurredAt: time.Unix(seconds, int64((data.Timestamp-float64(seconds))*1e9)),
+		},
+	}}, nil
+}
+
+// ------------------- Postmark ------------------- //
+
+type postmarkEvent struct {
+	RecordType  string            `json:"RecordType"`
+	Type        string            `json:"Type"`
+	MessageID   string            `json:"MessageID"`
+	Recipient   string            `json:"Recipient"`
+	Email       string            `json:"Email"`
+	Description string            `json:"Description"`
+	Details     string            `json:"Details"`
+	DeliveredAt time.Time         `json:"DeliveredAt"`
+	BouncedAt   time.Time         `json:"BouncedAt"`
+	ReceivedAt  time.Time         `json:"ReceivedAt"`
+	Metadata    map[string]string `json:"Metadata"`
+}
+
+func parsePostmarkEvents(c *gin.Context, body []byte) ([]providerEvent, error) {
+	if err := utils.VerifyBasicAuth(c.Request, middleware.Deps(c).Config.Providers.PostmarkUser, middleware.Deps(c).Config.Providers.PostmarkPassword); err != nil {
+		return nil, err
+	}
+
+	var item postmarkEvent
+	if err := json.Unmarshal(body, &item); err != nil {
+		return nil, err
+	}
+
+	event := models.EmailEvent{Recipient: models.EmailAddress(item.Recipient)}
+	switch item.RecordType {
+	case "Delivery":
+		event.Type = models.EventDelivered
+		event.OccurredAt = item.DeliveredAt
+	case "Bounce":
+		event.Type = models.EventDeferred
+		if item.Type == "HardBounce" || item.Type == "BadEmailAddress" {
+			event.Type = models.EventBounced
+		}
+		event.Recipient = models.EmailAddress(item.Email)
+		event.Reason = item.Description
+		event.OccurredAt = item.BouncedAt
+	case "SpamComplaint":
+		event.Type = models.EventComplained
+		event.Recipient = models.EmailAddress(item.Email)
+		event.OccurredAt = item.BouncedAt
+	case "Open":
+		event.Type = models.EventOpened
+		event.OccurredAt = item.ReceivedAt
+	case "Click":
+		event.Type = models.EventClicked
+		event.OccurredAt = item.ReceivedAt
+	default:
+		return nil, nil
+	}
+
+	// Postmark reports its own message ID, the email UUID travels as metadata
+	return []providerEvent{{EmailUUID: item.Metadata["email_uuid"], Event: event}}, nil
+}
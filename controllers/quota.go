@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// setQuotaHeaders tells callers how many emails they can still send in the tightest window
+func setQuotaHeaders(c *gin.Context, quota *models.QuotaWindow) {
+	c.Header("X-Quota-Limit", strconv.Itoa(quota.Limit))
+	c.Header("X-Quota-Remaining", strconv.FormatInt(quota.Remaining(), 10))
+	c.Header("X-Quota-Reset", strconv.FormatInt(quota.ResetAt.Unix(), 10))
+	c.Header("X-Quota-Scope", quota.Subject+"/"+quota.Period)
+}
+
+// enforceSendingQuota writes a 429 response and returns false when the company or website
+// has used up its quota
+func enforceSendingQuota(c *gin.Context, email *models.Email) bool {
+	quota, err := models.CheckSendingQuota(requestDB(c), middleware.Deps(c).Config.Quota, email.CompanyUUID, email.Website, time.Now())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return false
+	}
+	if quota == nil {
+		return true
+	}
+
+	setQuotaHeaders(c, quota)
+	if quota.Exceeded() {
+		retryAfter := int(time.Until(quota.ResetAt).Seconds()) + 1
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		utils.ErrorResponse(c, http.StatusTooManyRequests, quota)
+		return false
+	}
+
+	// The email about to be sent counts towards the quota
+	c.Header("X-Quota-Remaining", strconv.FormatInt(quota.Remaining()-1, 10))
+	return true
+}
+
+func CreateSendingQuota(c *gin.Context) {
+	validatedData, exists := c.Get("validatedData")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("data not found in context"))
+		return
+	}
+
+	quotaData, ok := validatedData.(models.SendingQuota)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid data format"))
+		return
+	}
+
+	// Replace the limits of an existing company or website quota
+	quota := models.SendingQuota{CompanyUUID: quotaData.CompanyUUID, Website: quotaData.Website}
+	if err := requestDB(c).
+		Where("company_uuid = ? AND website = ?", quota.CompanyUUID, quota.Website).
+		Assign(map[string]interface{}{
+			"hourly_limit":  quotaData.HourlyLimit,
+			"daily_limit":   quotaData.DailyLimit,
+			"monthly_limit": quotaData.MonthlyLimit,
+		}).
+		FirstOrCreate(&quota).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, quota, "Sending quota saved successfully")
+}
+
+func GetSendingQuotas(c *gin.Context) {
+	var quotas []models.SendingQuota
+
+	if err := requestDB(c).Order("id").Find(&quotas).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, quotas, "Sending quotas retrieved successfully")
+}
+
+func DeleteSendingQuotaByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid sending quota id"))
+		return
+	}
+
+	result := requestDB(c).Unscoped().Delete(&models.SendingQuota{}, id)
+	if result.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, errors.New("sending quota not found"))
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "Sending quota deleted successfully")
+}
@@ -0,0 +1,361 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// resolveSMTPConfig picks the SMTP settings for an email, a company override for the
+// website wins over a company wide override, then the website profile and finally the
+// SMTP_* defaults
+func resolveSMTPConfig(db *gorm.DB, defaults config.SMTPConfig, companyUUID uuid.UUID, website models.WebsiteConfig) (utils.SMTPConfig, error) {
+	profileName := website.SMTPProfile
+
+	var override models.CompanySMTPProfile
+	err := db.
+		Where("company_uuid = ? AND (website = ? OR website = '')", companyUUID, website.Code).
+		Order("website DESC").
+		First(&override).Error
+	if err == nil {
+		profileName = override.ProfileName
+	} else if err != gorm.ErrRecordNotFound {
+		return utils.SMTPConfig{}, err
+	}
+
+	if profileName == "" {
+		return utils.DefaultSMTPConfig(defaults)
+	}
+
+	var profile models.SMTPProfile
+	if err := db.Where("name = ?", profileName).First(&profile).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.SMTPConfig{}, errors.New("smtp profile " + profileName + " not found")
+		}
+		return utils.SMTPConfig{}, err
+	}
+
+	return profile.Config(), nil
+}
+
+// findSMTPProfile loads a profile by the name in the request URL
+func findSMTPProfile(c *gin.Context) (*models.SMTPProfile, bool) {
+	var profile models.SMTPProfile
+	if err := requestDB(c).Where("name = ?", c.Param("name")).First(&profile).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, errors.New("smtp profile not found"))
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return nil, false
+	}
+
+	return &profile, true
+}
+
+// requireSMTPProfile responds with 400 unless a profile with the name exists, websites and
+// company overrides may only reference stored profiles
+func requireSMTPProfile(c *gin.Context, name string) bool {
+	var count int64
+	if err := requestDB(c).Model(&models.SMTPProfile{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return false
+	}
+	if count == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("smtp profile not found"))
+		return false
+	}
+	return true
+}
+
+func CreateSMTPProfile(c *gin.Context) {
+	validatedData, exists := c.Get("validatedData")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("data not found in context"))
+		return
+	}
+
+	profileData, ok := validatedData.(models.SMTPProfile)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid data format"))
+		return
+	}
+
+	var count int64
+	if err := requestDB(c).Model(&models.SMTPProfile{}).Where("name = ?", profileData.Name).Count(&count).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+	if count > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, errors.New("smtp profile already exists"))
+		return
+	}
+
+	newProfile := models.SMTPProfile{
+		Name:          profileData.Name,
+		Host:          profileData.Host,
+		Port:          profileData.Port,
+		TLSMode:       profileData.TLSMode,
+		AuthMechanism: profileData.AuthMechanism,
+		Username:      profileData.Username,
+		Password:      profileData.Password,
+	}
+
+	if err := requestDB(c).Create(&newProfile).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, newProfile, "SMTP profile created successfully")
+}
+
+func GetSMTPProfiles(c *gin.Context) {
+	var profiles []models.SMTPProfile
+
+	if err := requestDB(c).Order("name").Find(&profiles).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, profiles, "SMTP profiles retrieved successfully")
+}
+
+func GetSMTPProfileByName(c *gin.Context) {
+	profile, ok := findSMTPProfile(c)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, profile, "SMTP profile retrieved successfully")
+}
+
+func UpdateSMTPProfileByName(c *gin.Context) {
+	profile, ok := findSMTPProfile(c)
+	if !ok {
+		return
+	}
+
+	var updateData models.SMTPProfile
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Update only the provided fields
+	if updateData.Host != "" {
+		profile.Host = updateData.Host
+	}
+
+	if updateData.Port != 0 {
+		if updateData.Port < 1 || updateData.Port > 65535 {
+			utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid port value"))
+			return
+		}
+		profile.Port = updateData.Port
+	}
+
+	if updateData.TLSMode != "" {
+		if !updateData.TLSMode.IsValid() {
+			utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid TLS mode value"))
+			return
+		}
+		profile.TLSMode = updateData.TLSMode
+	}
+
+	if updateData.AuthMechanism != "" {
+		if !updateData.AuthMechanism.IsValid() {
+			utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid auth mechanism value"))
+			return
+		}
+		profile.AuthMechanism = updateData.AuthMechanism
+	}
+
+	if updateData.Username != "" {
+		profile.Username = updateData.Username
+	}
+
+	if updateData.Password != "" {
+		profile.Password = updateData.Password
+	}
+
+	if err := requestDB(c).Save(profile).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, profile, "SMTP profile updated successfully")
+}
+
+func DeleteSMTPProfileByName(c *gin.Context) {
+	profile, ok := findSMTPProfile(c)
+	if !ok {
+		return
+	}
+
+	// Emails of websites or companies still using the profile would fail to send
+	var websites, overrides int64
+	if err := requestDB(c).Model(&models.WebsiteConfig{}).Where("smtp_profile = ?", profile.Name).Count(&websites).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err := requestDB(c).Model(&models.CompanySMTPProfile{}).Where("profile_name = ?", profile.Name).Count(&overrides).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+	if websites > 0 || overrides > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, errors.New("smtp profile is still used by websites or company overrides"))
+		return
+	}
+
+	// Deleted for good, a soft deleted row would keep holding the name in the unique index
+	if err := requestDB(c).Unscoped().Delete(profile).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "SMTP profile deleted successfully")
+}
+
+// TestSMTPProfile dials and authenticates against the profile without sending anything
+func TestSMTPProfile(c *gin.Context) {
+	profile, ok := findSMTPProfile(c)
+	if !ok {
+		return
+	}
+
+	start := time.Now()
+	sender, err := utils.DialSMTP(profile.Config())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadGateway, err)
+		return
+	}
+	sender.Close()
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"latency_ms": time.Since(start).Milliseconds()}, "SMTP connection successful")
+}
+
+// GetSMTPProfileStatus pings every stored profile concurrently, failures are reported by
+// reason only
+func GetSMTPProfileStatus(c *gin.Context) {
+	var profiles []models.SMTPProfile
+	if err := requestDB(c).Find(&profiles).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]componentStatus, len(profiles))
+
+	for _, profile := range profiles {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := utils.PingSMTP(ctx, profile.Config())
+			status := componentStatus{Status: "ok", LatencyMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				status.Status = "fail"
+				status.Error = utils.SMTPFailureReason(err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[profile.Name] = status
+		}()
+	}
+	wg.Wait()
+
+	utils.SuccessResponse(c, http.StatusOK, results, "SMTP profile status retrieved successfully")
+}
+
+// GetSMTPPoolStats returns the connection pool counters
+func GetSMTPPoolStats(c *gin.Context) {
+	// Only the SMTP pool keeps connection counters, other transports report empty stats
+	var stats utils.PoolStats
+	if pool, ok := middleware.Deps(c).Mailer.Transport.(*utils.SMTPPool); ok {
+		stats = pool.Stats()
+	}
+	utils.SuccessResponse(c, http.StatusOK, stats, "SMTP pool stats retrieved successfully")
+}
+
+func CreateCompanySMTPProfile(c *gin.Context) {
+	validatedData, exists := c.Get("validatedData")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("data not found in context"))
+		return
+	}
+
+	overrideData, ok := validatedData.(models.CompanySMTPProfile)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid data format"))
+		return
+	}
+
+	if !requireSMTPProfile(c, overrideData.ProfileName) {
+		return
+	}
+
+	// Replace an existing override for the same company and website
+	override := models.CompanySMTPProfile{CompanyUUID: overrideData.CompanyUUID, Website: overrideData.Website}
+	if err := requestDB(c).
+		Where("company_uuid = ? AND website = ?", overrideData.CompanyUUID, overrideData.Website).
+		Assign(models.CompanySMTPProfile{ProfileName: overrideData.ProfileName}).
+		FirstOrCreate(&override).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, override, "Company SMTP profile saved successfully")
+}
+
+func GetCompanySMTPProfiles(c *gin.Context) {
+	if !isValidUUID(c.Param("uuid")) {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid UUID format in request URL"))
+		return
+	}
+
+	var overrides []models.CompanySMTPProfile
+	if err := requestDB(c).Where("company_uuid = ?", c.Param("uuid")).Find(&overrides).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, overrides, "Company SMTP profiles retrieved successfully")
+}
+
+func DeleteCompanySMTPProfile(c *gin.Context) {
+	if !isValidUUID(c.Param("uuid")) {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid UUID format in request URL"))
+		return
+	}
+
+	// An empty website query parameter removes the company wide override
+	result := requestDB(c).
+		Where("company_uuid = ? AND website = ?", c.Param("uuid"), c.Query("website")).
+		Delete(&models.CompanySMTPProfile{})
+	if result.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, errors.New("company smtp profile not found"))
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "Company SMTP profile deleted successfully")
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// findSuppression loads a suppression by the ID in the request URL
+func findSuppression(c *gin.Context) (*models.Suppression, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid ID format in request URL"))
+		return nil, false
+	}
+
+	var suppression models.Suppression
+	if err := currentPrincipal(c).ScopeToCompany(requestDB(c)).First(&suppression, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, errors.New("suppression not found"))
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return nil, false
+	}
+
+	return &suppression, true
+}
+
+func CreateSuppression(c *gin.Context) {
+	validatedData, exists := c.Get("validatedData")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("data not found in context"))
+		return
+	}
+
+	suppressionData, ok := validatedData.(models.Suppression)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid data format"))
+		return
+	}
+
+	// Company keys can only suppress addresses for their own company
+	principal := currentPrincipal(c)
+	if !principal.Admin && (suppressionData.Scope != models.ScopeCompany || !principal.CanAccessCompany(suppressionData.CompanyUUID)) {
+		utils.ErrorResponse(c, http.StatusForbidden, errors.New("only company suppressions for your own company can be created"))
+		return
+	}
+
+	newSuppression := models.Suppression{
+		Address:     suppressionData.Address,
+		Scope:       suppressionData.Scope,
+		Website:     suppressionData.Website,
+		CompanyUUID: suppressionData.CompanyUUID,
+		Reason:      suppressionData.Reason,
+		ExpiresAt:   suppressionData.ExpiresAt,
+	}
+
+	if err := requestDB(c).Create(&newSuppression).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, newSuppression, "Suppression created successfully")
+}
+
+func GetSuppressions(c *gin.Context) {
+	var suppressions []models.Suppression
+
+	// Optionally filter by address
+	query := currentPrincipal(c).ScopeToCompany(requestDB(c)).Order("created_at DESC")
+	if address := c.Query("address"); address != "" {
+		query = query.Where("address = ?", strings.ToLower(address))
+	}
+
+	if err := query.Find(&suppressions).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, suppressions, "Suppressions retrieved successfully")
+}
+
+func GetSuppressionByID(c *gin.Context) {
+	suppression, ok := findSuppression(c)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, suppression, "Suppression retrieved successfully")
+}
+
+func UpdateSuppressionByID(c *gin.Context) {
+	suppression, ok := findSuppression(c)
+	if !ok {
+		return
+	}
+
+	var updateData models.Suppression
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Only the reason and expiry can change, a different address or scope is a new suppression
+	if updateData.Reason != "" {
+		if !updateData.Reason.IsValid() {
+			utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid reason value"))
+			return
+		}
+		suppression.Reason = updateData.Reason
+	}
+
+	if updateData.ExpiresAt != nil {
+		suppression.ExpiresAt = updateData.ExpiresAt
+	}
+
+	if err := requestDB(c).Save(suppression).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, suppression, "Suppression updated successfully")
+}
+
+func DeleteSuppressionByID(c *gin.Context) {
+	suppression, ok := findSuppression(c)
+	if !ok {
+		return
+	}
+
+	// Hard delete so the address can be suppressed again later
+	if err := requestDB(c).Unscoped().Delete(suppression).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "Suppression deleted successfully")
+}
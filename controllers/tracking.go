@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TrackOpen records an open and always serves the pixel, invalid tokens are silently ignored
+func TrackOpen(c *gin.Context) {
+	secret := middleware.Deps(c).Config.Security.TokenSecret
+
+	var token utils.TrackingToken
+	if err := utils.VerifyToken(secret, strings.TrimSuffix(c.Param("token"), ".gif"), &token); err == nil {
+		if emailUUID, err := uuid.Parse(token.EmailUUID); err == nil {
+			requestDB(c).Create(&models.EmailEvent{
+				EmailUUID:  emailUUID,
+				Type:       models.EventOpened,
+				Provider:   "pixel",
+				UserAgent:  c.Request.UserAgent(),
+				IPHash:     utils.HashIP(secret, c.ClientIP()),
+				OccurredAt: time.Now(),
+			})
+		}
+	}
+
+	c.Header("Cache-Control", "no-store, no-cache, must-revalidate, private")
+	c.Data(http.StatusOK, "image/gif", utils.TransparentGIF)
+}
+
+// TrackClick records a click and redirects to the original link, the allowlist is checked
+// again so tokens signed before a host was removed stop redirecting
+func TrackClick(c *gin.Context) {
+	cfg := middleware.Deps(c).Config
+
+	var token utils.TrackingToken
+	if err := utils.VerifyToken(cfg.Security.TokenSecret, c.Param("token"), &token); err != nil || !utils.IsTrackableLink(cfg.Tracking.ClickAllowedHosts, token.URL) {
+		utils.ErrorResponse(c, http.StatusNotFound, errors.New("link not found"))
+		return
+	}
+
+	if emailUUID, err := uuid.Parse(token.EmailUUID); err == nil {
+		requestDB(c).Create(&models.EmailEvent{
+			EmailUUID:  emailUUID,
+			Type:       models.EventClicked,
+			Provider:   "redirect",
+			URL:        token.URL,
+			UserAgent:  c.Request.UserAgent(),
+			IPHash:     utils.HashIP(cfg.Security.TokenSecret, c.ClientIP()),
+			OccurredAt: time.Now(),
+		})
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, token.URL)
+}
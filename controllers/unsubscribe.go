@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// unsubscribePage is shown for GET requests, unsubscribing on GET would let link scanners
+// opt recipients out so the page posts back instead (RFC 8058)
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charset="UTF-8" />
+    <meta name="viewport" content="width=device-width, initial-scale=1.0" />
+    <title>Unsubscribe</title>
+  </head>
+  <body>
+    {{ if .Done }}
+    <p>{{ .Recipient }} has been unsubscribed.</p>
+    {{ else }}
+    <p>Unsubscribe {{ .Recipient }} from these emails?</p>
+    <form method="post">
+      <input type="hidden" name="List-Unsubscribe" value="One-Click" />
+      <button type="submit">Unsubscribe</button>
+    </form>
+    {{ end }}
+  </body>
+</html>
+`))
+
+// unsubscribeURL builds the signed unsubscribe link for a recipient, it is empty when
+// PUBLIC_BASE_URL is not configured
+func unsubscribeURL(cfg *config.Config, recipient models.EmailAddress, website models.Website, category string) (string, error) {
+	if utils.PublicURL(cfg.Server.PublicBaseURL, "") == "" {
+		return "", nil
+	}
+
+	token, err := utils.SignToken(cfg.Security.TokenSecret, models.UnsubscribeToken{Recipient: recipient, Website: website, Category: category})
+	if err != nil {
+		return "", err
+	}
+	return utils.PublicURL(cfg.Server.PublicBaseURL, "/unsubscribe/"+token), nil
+}
+
+func renderUnsubscribePage(c *gin.Context, token models.UnsubscribeToken, done bool) {
+	var body bytes.Buffer
+	if err := unsubscribePage.Execute(&body, gin.H{"Recipient": token.Recipient, "Done": done}); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body.Bytes())
+}
+
+func ShowUnsubscribe(c *gin.Context) {
+	var token models.UnsubscribeToken
+	if err := utils.VerifyToken(middleware.Deps(c).Config.Security.TokenSecret, c.Param("token"), &token); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid unsubscribe link"))
+		return
+	}
+
+	renderUnsubscribePage(c, token, false)
+}
+
+// Unsubscribe handles both the confirmation form and one-click POSTs from mail clients
+func Unsubscribe(c *gin.Context) {
+	var token models.UnsubscribeToken
+	if err := utils.VerifyToken(middleware.Deps(c).Config.Security.TokenSecret, c.Param("token"), &token); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid unsubscribe link"))
+		return
+	}
+
+	if err := models.RecordUnsubscribe(requestDB(c), token); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	renderUnsubscribePage(c, token, true)
+}
+
+func GetUnsubscribePreferences(c *gin.Context) {
+	var preferences []models.UnsubscribePreference
+
+	// Optionally filter by recipient
+	query := requestDB(c).Order("created_at DESC")
+	if recipient := c.Query("recipient"); recipient != "" {
+		query = query.Where("recipient = ?", strings.ToLower(recipient))
+	}
+
+	if err := query.Find(&preferences).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, preferences, "Unsubscribe preferences retrieved successfully")
+}
+
+// DeleteUnsubscribePreference resubscribes the recipient
+func DeleteUnsubscribePreference(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid ID format in request URL"))
+		return
+	}
+
+	result := requestDB(c).Unscoped().Delete(&models.UnsubscribePreference{}, id)
+	if result.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, errors.New("unsubscribe preference not found"))
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "Unsubscribe preference deleted successfully")
+}
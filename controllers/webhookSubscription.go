@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// findWebhookSubscription loads a subscription by the ID in the request URL
+func findWebhookSubscription(c *gin.Context) (*models.WebhookSubscription, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid ID format in request URL"))
+		return nil, false
+	}
+
+	var subscription models.WebhookSubscription
+	if err := currentPrincipal(c).ScopeToCompany(requestDB(c)).First(&subscription, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, errors.New("webhook subscription not found"))
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return nil, false
+	}
+
+	return &subscription, true
+}
+
+func CreateWebhookSubscription(c *gin.Context) {
+	validatedData, exists := c.Get("validatedData")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("data not found in context"))
+		return
+	}
+
+	subscriptionData, ok := validatedData.(models.WebhookSubscription)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid data format"))
+		return
+	}
+
+	// Company keys can only subscribe to their own company, an empty company means every company
+	if !currentPrincipal(c).CanAccessCompany(subscriptionData.CompanyUUID) {
+		utils.ErrorResponse(c, http.StatusForbidden, errors.New("company is not accessible with these credentials"))
+		return
+	}
+
+	// Deliveries are sent from inside the network, they must not reach internal services
+	if err := utils.ValidateWebhookURL(c.Request.Context(), subscriptionData.URL); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	newSubscription := models.WebhookSubscription{
+		CompanyUUID: subscriptionData.CompanyUUID,
+		Website:     subscriptionData.Website,
+		URL:         subscriptionData.URL,
+		Secret:      subscriptionData.Secret,
+		EventTypes:  subscriptionData.EventTypes,
+	}
+
+	if err := requestDB(c).Create(&newSubscription).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, newSubscription, "Webhook subscription created successfully")
+}
+
+func GetWebhookSubscriptions(c *gin.Context) {
+	var subscriptions []models.WebhookSubscription
+
+	if err := currentPrincipal(c).ScopeToCompany(requestDB(c)).Order("id").Find(&subscriptions).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, subscriptions, "Webhook subscriptions retrieved successfully")
+}
+
+func GetWebhookSubscriptionByID(c *gin.Context) {
+	subscription, ok := findWebhookSubscription(c)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, subscription, "Webhook subscription retrieved successfully")
+}
+
+func DeleteWebhookSubscriptionByID(c *gin.Context) {
+	subscription, ok := findWebhookSubscription(c)
+	if !ok {
+		return
+	}
+
+	if err := requestDB(c).Delete(subscription).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "Webhook subscription deleted successfully")
+}
+
+// GetWebhookDeliveries returns the delivery log of a subscription, newest first
+func GetWebhookDeliveries(c *gin.Context) {
+	subscription, ok := findWebhookSubscription(c)
+	if !ok {
+		return
+	}
+
+	query := requestDB(c).Where("subscription_id = ?", subscription.ID).Order("id DESC").Limit(100)
+	if state := c.Query("state"); state != "" {
+		query = query.Where("state = ?", state)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Find(&deliveries).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, deliveries, "Webhook deliveries retrieved successfully")
+}
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// findWebsite loads a website from the registry by the code in the request URL
+func findWebsite(c *gin.Context) (*models.WebsiteConfig, bool) {
+	code := models.Website(c.Param("code"))
+	if !code.IsValid() {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid website code in request URL"))
+		return nil, false
+	}
+
+	var website models.WebsiteConfig
+	if err := requestDB(c).Where("code = ?", code).First(&website).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, errors.New("website not found"))
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return nil, false
+	}
+
+	return &website, true
+}
+
+func CreateWebsite(c *gin.Context) {
+	validatedData, exists := c.Get("validatedData")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("data not found in context"))
+		return
+	}
+
+	websiteData, ok := validatedData.(models.WebsiteConfig)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid data format"))
+		return
+	}
+
+	// Reject duplicate codes with a clear message instead of a constraint error
+	var count int64
+	if err := requestDB(c).Model(&models.WebsiteConfig{}).Where("code = ?", websiteData.Code).Count(&count).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+	if count > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, errors.New("website already exists"))
+		return
+	}
+
+	if websiteData.SMTPProfile != "" && !requireSMTPProfile(c, websiteData.SMTPProfile) {
+		return
+	}
+
+	newWebsite := models.WebsiteConfig{
+		Code:           websiteData.Code,
+		DisplayName:    websiteData.DisplayName,
+		DefaultSender:  websiteData.DefaultSender,
+		ReplyTo:        websiteData.ReplyTo,
+		LogoURL:        websiteData.LogoURL,
+		PrimaryColor:   websiteData.PrimaryColor,
+		FooterText:     websiteData.FooterText,
+		AllowedSources: websiteData.AllowedSources,
+		SMTPProfile:    websiteData.SMTPProfile,
+		TrackOpens:     websiteData.TrackOpens,
+		TrackClicks:    websiteData.TrackClicks,
+	}
+
+	if err := requestDB(c).Create(&newWebsite).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, newWebsite, "Website created successfully")
+}
+
+func GetWebsites(c *gin.Context) {
+	var websites []models.WebsiteConfig
+
+	if err := requestDB(c).Order("code").Find(&websites).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, websites, "Websites retrieved successfully")
+}
+
+func GetWebsiteByCode(c *gin.Context) {
+	website, ok := findWebsite(c)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, website, "Website retrieved successfully")
+}
+
+func UpdateWebsiteByCode(c *gin.Context) {
+	website, ok := findWebsite(c)
+	if !ok {
+		return
+	}
+
+	// Optional fields are pointers so an omitted field can be told apart from an empty
+	// value, which clears it
+	var updateData struct {
+		models.WebsiteConfig
+		ReplyTo      *models.EmailAddress `json:"reply_to"`
+		LogoURL      *string              `json:"logo_url"`
+		PrimaryColor *string              `json:"primary_color"`
+		FooterText   *string              `json:"footer_text"`
+		SMTPProfile  *string              `json:"smtp_profile"`
+		TrackOpens   *bool                `json:"track_opens"`
+		TrackClicks  *bool                `json:"track_clicks"`
+	}
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Update only the provided fields, the code is immutable since templates are stored under it
+	if updateData.DisplayName != "" {
+		website.DisplayName = updateData.DisplayName
+	}
+
+	if updateData.DefaultSender != "" {
+		if !updateData.DefaultSender.IsValid() {
+			utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid default sender email address"))
+			return
+		}
+		website.DefaultSender = updateData.DefaultSender
+	}
+
+	if updateData.ReplyTo != nil {
+		if *updateData.ReplyTo != "" && !updateData.ReplyTo.IsValid() {
+			utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid reply to email address"))
+			return
+		}
+		website.ReplyTo = *updateData.ReplyTo
+	}
+
+	if updateData.LogoURL != nil {
+		website.LogoURL = *updateData.LogoURL
+	}
+
+	if updateData.PrimaryColor != nil {
+		website.PrimaryColor = *updateData.PrimaryColor
+	}
+
+	if updateData.FooterText != nil {
+		website.FooterText = *updateData.FooterText
+	}
+
+	if updateData.AllowedSources != nil {
+		for _, source := range updateData.AllowedSources {
+			if !source.IsValid() {
+				utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid source value in allowed sources"))
+				return
+			}
+		}
+		website.AllowedSources = updateData.AllowedSources
+	}
+
+	if updateData.SMTPProfile != nil {
+		// An empty name goes back to the SMTP_* defaults
+		if *updateData.SMTPProfile != "" && !requireSMTPProfile(c, *updateData.SMTPProfile) {
+			return
+		}
+		website.SMTPProfile = *updateData.SMTPProfile
+	}
+
+	if updateData.TrackOpens != nil {
+		website.TrackOpens = *updateData.TrackOpens
+	}
+
+	if updateData.TrackClicks != nil {
+		website.TrackClicks = *updateData.TrackClicks
+	}
+
+	if err := requestDB(c).Save(website).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, website, "Website updated successfully")
+}
+
+func DeleteWebsiteByCode(c *gin.Context) {
+	website, ok := findWebsite(c)
+	if !ok {
+		return
+	}
+
+	// Deleted for good, a soft deleted row would keep holding the code in the unique index
+	if err := requestDB(c).Unscoped().Delete(website).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, nil, "Website deleted successfully")
+}
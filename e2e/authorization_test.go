@@ -0,0 +1,105 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const jwtStaticKey = "e2e-jwt-static-key"
+
+// dashboardToken signs a JWT for a dashboard user with the roles, accepted by JWT_STATIC_KEY
+func dashboardToken(t *testing.T, roles ...string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":       "dashboard-user",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+		"roles":     roles,
+		"companies": []string{companyUUID},
+	}).SignedString([]byte(jwtStaticKey))
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return token
+}
+
+func TestWebsiteRegistryRequiresTemplateWrite(t *testing.T) {
+	h := newHarness(t)
+	sender := h.companyKey(companyUUID, "email:send", "email:read")
+	editor := h.companyKey(companyUUID, "email:send", "email:read", "template:write")
+
+	h.doAs(sender, http.MethodGet, "/api/v1/website", nil).expect(t, http.StatusOK)
+	h.doAs(sender, http.MethodPost, "/api/v1/website", map[string]interface{}{
+		"code": "SHOP", "display_name": "Shop", "default_sender": "shop@example.com",
+	}).expect(t, http.StatusForbidden)
+	h.doAs(sender, http.MethodPatch, "/api/v1/website/IK", map[string]interface{}{"display_name": "Hijacked"}).
+		expect(t, http.StatusForbidden)
+
+	h.doAs(editor, http.MethodPost, "/api/v1/website", map[string]interface{}{
+		"code": "SHOP", "display_name": "Shop", "default_sender": "shop@example.com",
+	}).expect(t, http.StatusCreated)
+	h.doAs(editor, http.MethodPatch, "/api/v1/website/IK", map[string]interface{}{"footer_text": "Inventory Keeper Ltd"}).
+		expect(t, http.StatusOK)
+
+	// Removing a website stays with admins
+	h.doAs(editor, http.MethodDelete, "/api/v1/website/SHOP", nil).expect(t, http.StatusForbidden)
+	h.do(http.MethodDelete, "/api/v1/website/SHOP", nil).expect(t, http.StatusOK)
+
+	var website struct {
+		DisplayName string `json:"display_name"`
+		FooterText  string `json:"footer_text"`
+	}
+	h.do(http.MethodGet, "/api/v1/website/IK", nil).expect(t, http.StatusOK).decode(t, &website)
+	if website.DisplayName != "Inventory Keeper" || website.FooterText != "Inventory Keeper Ltd" {
+		t.Fatalf("unexpected website after updates: %+v", website)
+	}
+}
+
+func TestManagersCanUpdateBranding(t *testing.T) {
+	h := newHarness(t, func(cfg *config.Config) { cfg.Auth.JWTStaticKey = jwtStaticKey })
+
+	h.doAs(dashboardToken(t, "editor"), http.MethodPatch, "/api/v1/website/IK", map[string]interface{}{"primary_color": "#112233"}).
+		expect(t, http.StatusForbidden)
+	h.doAs(dashboardToken(t, "manager"), http.MethodPatch, "/api/v1/website/IK", map[string]interface{}{"primary_color": "#445566"}).
+		expect(t, http.StatusOK)
+	h.doAs(dashboardToken(t, "admin"), http.MethodPatch, "/api/v1/website/IK", map[string]interface{}{"footer_text": "Inventory Keeper Ltd"}).
+		expect(t, http.StatusOK)
+
+	var website struct {
+		PrimaryColor string `json:"primary_color"`
+	}
+	h.do(http.MethodGet, "/api/v1/website/IK", nil).expect(t, http.StatusOK).decode(t, &website)
+	if website.PrimaryColor != "#445566" {
+		t.Fatalf("expected the manager's branding, got %+v", website)
+	}
+}
+
+func TestCompanyKeysCannotSetProviderStatuses(t *testing.T) {
+	h := newHarness(t)
+	key := h.companyKey(companyUUID, "email:send", "email:read", "email:write")
+
+	var created email
+	h.doAs(key, http.MethodPost, "/api/v1/email", newEmail("IK", "TRIAL_CREATED", "bob@example.com", `{}`)).
+		expect(t, http.StatusCreated).decode(t, &created)
+
+	for _, status := range []string{"BOUNCED", "COMPLAINED", "DELIVERED"} {
+		h.doAs(key, http.MethodPatch, "/api/v1/email/"+created.UUID, map[string]interface{}{"status": status}).
+			expect(t, http.StatusForbidden)
+	}
+	h.doAs(key, http.MethodPatch, "/api/v1/email/"+created.UUID, map[string]interface{}{"status": "FAILED"}).
+		expect(t, http.StatusOK)
+
+	// Nothing was suppressed, the next email to the recipient is sent
+	h.doAs(key, http.MethodPost, "/api/v1/email", newEmail("IK", "TRIAL_CREATED", "bob@example.com", `{}`)).
+		expect(t, http.StatusCreated)
+	if len(h.smtp.Messages()) != 2 {
+		t.Fatalf("expected 2 delivered messages, got %d", len(h.smtp.Messages()))
+	}
+
+	// Admins can still record a bounce by hand
+	h.do(http.MethodPatch, "/api/v1/email/"+created.UUID, map[string]interface{}{"status": "BOUNCED"}).
+		expect(t, http.StatusOK)
+}
@@ -0,0 +1,342 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/farhan-nahid/email-service/app"
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+)
+
+const companyUUID = "7b0e3f2a-8d4c-4f6e-9a1b-2c3d4e5f6a7b"
+
+// email holds the fields of the email responses the suite asserts on
+type email struct {
+	UUID         string `json:"uuid"`
+	Name         string `json:"name"`
+	Sender       string `json:"sender"`
+	Recipient    string `json:"receiver"`
+	Subject      string `json:"subject"`
+	Status       string `json:"status"`
+	StatusReason string `json:"status_reason"`
+	Source       string `json:"source"`
+	Website      string `json:"website"`
+	MessageID    string `json:"message_id"`
+}
+
+// newEmail returns a valid create request, callers override the fields they test
+func newEmail(website, source, recipient, payload string) map[string]interface{} {
+	return map[string]interface{}{
+		"company_uuid": companyUUID,
+		"name":         "Bob",
+		"receiver":     recipient,
+		"subject":      "Hello from " + website,
+		"status":       "SENT",
+		"source":       source,
+		"website":      website,
+		"payload":      payload,
+	}
+}
+
+func TestEmailLifecycle(t *testing.T) {
+	h := newHarness(t)
+
+	var created email
+	h.do(http.MethodPost, "/api/v1/email", newEmail("IK", "TRIAL_CREATED", "bob@example.com", `{}`)).
+		expect(t, http.StatusCreated).decode(t, &created)
+	if created.Status != "SENT" || created.Sender != "noreply@example.com" {
+		t.Fatalf("unexpected created email %+v", created)
+	}
+
+	// The message reached the SMTP server with the envelope and headers of the email
+	messages := h.smtp.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+	if messages[0].From != "noreply@example.com" || len(messages[0].To) != 1 || messages[0].To[0] != "bob@example.com" {
+		t.Fatalf("unexpected envelope from %s to %v", messages[0].From, messages[0].To)
+	}
+	msg := parseMessage(t, messages[0].Data)
+	if got := msg.Header.Get("From"); got != `"Inventory Keeper" <noreply@example.com>` {
+		t.Errorf("unexpected From header %q", got)
+	}
+	if got := msg.Header.Get("Subject"); got != "Hello from IK" {
+		t.Errorf("unexpected Subject header %q", got)
+	}
+	if got := msg.Header.Get("Message-Id"); got != created.MessageID {
+		t.Errorf("Message-ID header %q does not match the stored %q", got, created.MessageID)
+	}
+
+	// List and get
+	var listed []email
+	h.do(http.MethodGet, "/api/v1/email", nil).expect(t, http.StatusOK).decode(t, &listed)
+	if len(listed) != 1 || listed[0].UUID != created.UUID {
+		t.Fatalf("expected the created email in the list, got %+v", listed)
+	}
+	h.do(http.MethodGet, "/api/v1/email/"+created.UUID, nil).expect(t, http.StatusOK)
+	h.do(http.MethodGet, "/api/v1/email/not-a-uuid", nil).expect(t, http.StatusBadRequest)
+
+	// Update
+	var updated email
+	h.do(http.MethodPatch, "/api/v1/email/"+created.UUID, map[string]interface{}{"subject": "Updated subject"}).
+		expect(t, http.StatusOK).decode(t, &updated)
+	if updated.Subject != "Updated subject" || updated.Recipient != "bob@example.com" {
+		t.Fatalf("only the subject should change, got %+v", updated)
+	}
+	h.do(http.MethodPatch, "/api/v1/email/"+created.UUID, map[string]interface{}{"status": "UNKNOWN"}).
+		expect(t, http.StatusBadRequest)
+
+	// Delete moves the email to the deleted list
+	h.do(http.MethodGet, "/api/v1/email/deleted", nil).expect(t, http.StatusNotFound)
+	h.do(http.MethodDelete, "/api/v1/email/"+created.UUID, nil).expect(t, http.StatusOK)
+	h.do(http.MethodGet, "/api/v1/email/"+created.UUID, nil).expect(t, http.StatusNotFound)
+	h.do(http.MethodGet, "/api/v1/email", nil).expect(t, http.StatusNotFound)
+	h.do(http.MethodDelete, "/api/v1/email/"+created.UUID, nil).expect(t, http.StatusNotFound)
+
+	var deleted []email
+	h.do(http.MethodGet, "/api/v1/email/deleted", nil).expect(t, http.StatusOK).decode(t, &deleted)
+	if len(deleted) != 1 || deleted[0].UUID != created.UUID || deleted[0].Subject != "Updated subject" {
+		t.Fatalf("expected the deleted email, got %+v", deleted)
+	}
+
+	// Restore brings it back
+	h.do(http.MethodPost, "/api/v1/email/"+created.UUID+"/restore", nil).expect(t, http.StatusOK)
+	h.do(http.MethodGet, "/api/v1/email/"+created.UUID, nil).expect(t, http.StatusOK)
+	h.do(http.MethodGet, "/api/v1/email/deleted", nil).expect(t, http.StatusNotFound)
+}
+
+func TestCreateEmailValidation(t *testing.T) {
+	h := newHarness(t)
+
+	invalid := newEmail("IK", "TRIAL_CREATED", "not-an-address", `{}`)
+	h.do(http.MethodPost, "/api/v1/email", invalid).expect(t, http.StatusBadRequest)
+
+	invalid = newEmail("IK", "TRIAL_CREATED", "bob@example.com", `not json`)
+	h.do(http.MethodPost, "/api/v1/email", invalid).expect(t, http.StatusBadRequest)
+
+	if len(h.smtp.Messages()) != 0 {
+		t.Fatal("invalid requests must not send email")
+	}
+}
+
+func TestTemplateRenderingPerWebsiteAndSource(t *testing.T) {
+	tests := []struct {
+		website string
+		source  string
+		payload string
+		want    []string
+	}{
+		{"IK", "TRIAL_CREATED", `{}`, []string{"Welcome to Inventory Keeper, Bob", "IK trial created"}},
+		{"MYE", "TRIAL_CREATED", `{}`, []string{"Welcome to Manage Your Ecommerce, Bob", "MYE trial created"}},
+		{"AK", "TRIAL_CREATED", `{}`, []string{"Welcome to Attendance Keeper, Bob", "AK trial created"}},
+		{"MYE", "SUBSCRIPTION_CREATED", `{"plan":"Pro"}`, []string{"Thanks for subscribing, Bob", "Plan: Pro"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.website+"/"+test.source, func(t *testing.T) {
+			h := newHarness(t)
+
+			h.do(http.MethodPost, "/api/v1/email", newEmail(test.website, test.source, "bob@example.com", test.payload)).
+				expect(t, http.StatusCreated)
+
+			messages := h.smtp.Messages()
+			if len(messages) != 1 {
+				t.Fatalf("expected 1 message, got %d", len(messages))
+			}
+			html, ok := parseMessage(t, messages[0].Data).Part("text/html")
+			if !ok {
+				t.Fatal("message has no text/html part")
+			}
+			for _, want := range test.want {
+				if !strings.Contains(string(html.Body), want) {
+					t.Errorf("rendered body does not contain %q:\n%s", want, html.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestInvoiceAttachment(t *testing.T) {
+	invoice := []byte("%PDF-1.4\n% fake invoice\n")
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/invoice.pdf" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(invoice)
+	}))
+	defer files.Close()
+
+	h := newHarness(t)
+	h.do(http.MethodPost, "/api/v1/email", newEmail("MYE", "SUBSCRIPTION_CREATED", "bob@example.com",
+		`{"plan":"Pro","invoiceLink":"`+files.URL+`/invoice.pdf"}`)).expect(t, http.StatusCreated)
+
+	messages := h.smtp.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+	msg := parseMessage(t, messages[0].Data)
+	if !strings.HasPrefix(msg.Header.Get("Content-Type"), "multipart/mixed") {
+		t.Fatalf("expected a multipart/mixed message, got %q", msg.Header.Get("Content-Type"))
+	}
+	if _, ok := msg.Part("text/html"); !ok {
+		t.Error("message has no text/html part")
+	}
+
+	attachment, ok := msg.Attachment("invoice.pdf")
+	if !ok {
+		t.Fatal("message has no invoice.pdf attachment")
+	}
+	if attachment.ContentType != "application/pdf" {
+		t.Errorf("unexpected attachment content type %q", attachment.ContentType)
+	}
+	if !bytes.Equal(attachment.Body, invoice) {
+		t.Errorf("attachment content differs from the downloaded invoice: %q", attachment.Body)
+	}
+
+	// A missing invoice fails the email instead of attaching the error page
+	var failed email
+	h.do(http.MethodPost, "/api/v1/email", newEmail("MYE", "SUBSCRIPTION_CREATED", "alice@example.com",
+		`{"plan":"Pro","invoiceLink":"`+files.URL+`/missing.pdf"}`)).expect(t, http.StatusInternalServerError)
+	assertFailed(t, h, "alice@example.com", &failed)
+	if len(h.smtp.Messages()) != 1 {
+		t.Fatal("an email without its invoice must not be sent")
+	}
+}
+
+func TestSMTPRejectsRecipient(t *testing.T) {
+	h := newHarness(t)
+	h.smtp.Reject("rejected@example.com")
+
+	result := h.do(http.MethodPost, "/api/v1/email", newEmail("IK", "TRIAL_CREATED", "rejected@example.com", `{}`)).
+		expect(t, http.StatusInternalServerError)
+	if result.Success || !strings.Contains(result.Error, "550") {
+		t.Fatalf("expected the 550 reply in the error, got %q", result.Error)
+	}
+
+	var failed email
+	assertFailed(t, h, "rejected@example.com", &failed)
+	if !strings.Contains(failed.StatusReason, "550") {
+		t.Errorf("expected the 550 reply as status reason, got %q", failed.StatusReason)
+	}
+	if len(h.smtp.Messages()) != 0 {
+		t.Fatalf("rejected email was delivered")
+	}
+
+	// The pooled connection stays usable after a rejected recipient
+	h.do(http.MethodPost, "/api/v1/email", newEmail("IK", "TRIAL_CREATED", "bob@example.com", `{}`)).
+		expect(t, http.StatusCreated)
+	if len(h.smtp.Messages()) != 1 {
+		t.Fatalf("expected the next email to be delivered")
+	}
+}
+
+func TestMissingTemplate(t *testing.T) {
+	h := newHarness(t)
+
+	// There is no AK/SUBSCRIPTION_CREATED.html in testdata
+	result := h.do(http.MethodPost, "/api/v1/email", newEmail("AK", "SUBSCRIPTION_CREATED", "bob@example.com", `{}`)).
+		expect(t, http.StatusInternalServerError)
+	if result.Success || result.Error == "" {
+		t.Fatalf("expected an error response, got %+v", result)
+	}
+
+	var failed email
+	assertFailed(t, h, "bob@example.com", &failed)
+	if len(h.smtp.Messages()) != 0 {
+		t.Fatal("an email without a template must not be sent")
+	}
+}
+
+func TestInstancesAreIsolated(t *testing.T) {
+	first := newHarness(t)
+	second := newHarness(t)
+
+	first.do(http.MethodPost, "/api/v1/email", newEmail("IK", "TRIAL_CREATED", "bob@example.com", `{}`)).
+		expect(t, http.StatusCreated)
+
+	second.do(http.MethodGet, "/api/v1/email", nil).expect(t, http.StatusNotFound)
+	if len(second.smtp.Messages()) != 0 {
+		t.Fatal("the second instance must not use the SMTP server of the first")
+	}
+	if !strings.Contains(first.logs.String(), `"msg":"email sent"`) || strings.Contains(second.logs.String(), `"msg":"email sent"`) {
+		t.Fatal("the send must only be logged by the first instance")
+	}
+}
+
+func TestInstancesUseTheirOwnSecrets(t *testing.T) {
+	secrets := func(key, secret string) func(cfg *config.Config) {
+		return func(cfg *config.Config) {
+			cfg.Security.EncryptionKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat(key, 32)))
+			cfg.Security.TokenSecret = secret
+		}
+	}
+	first := newHarness(t, secrets("a", "first-token-secret"))
+	second := newHarness(t, secrets("b", "second-token-secret"))
+
+	for _, h := range []*harness{first, second} {
+		h.do(http.MethodPost, "/api/v1/smtp-profile", map[string]interface{}{
+			"name": "relay", "host": "127.0.0.1", "port": 2525, "tls_mode": "NONE", "password": "relay-password",
+		}).expect(t, http.StatusCreated)
+	}
+
+	// Each instance encrypts with its own key and reads its profile back
+	var stored string
+	first.app.DB.Raw("SELECT password FROM smtp_profiles").Scan(&stored)
+	if !strings.HasPrefix(stored, "enc:v1:") {
+		t.Fatalf("the password is not encrypted at rest: %q", stored)
+	}
+	if _, err := utils.Decrypt(second.app.Config.Security.EncryptionKey, stored); err == nil {
+		t.Fatal("the second instance must not decrypt secrets of the first")
+	}
+	var profile models.SMTPProfile
+	if err := first.app.DB.First(&profile).Error; err != nil || profile.Password != "relay-password" {
+		t.Fatalf("expected the first instance to decrypt its password, got %q: %v", profile.Password, err)
+	}
+
+	// Signed links of one instance are rejected by the other
+	token, err := utils.SignToken("first-token-secret", models.UnsubscribeToken{Recipient: "bob@example.com", Website: "IK", Category: "marketing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorder := first.serve(httptest.NewRequest(http.MethodGet, "/unsubscribe/"+token, nil)); recorder.Code != http.StatusOK {
+		t.Fatalf("expected the first instance to accept its link, got %d", recorder.Code)
+	}
+	if recorder := second.serve(httptest.NewRequest(http.MethodGet, "/unsubscribe/"+token, nil)); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected the second instance to reject the link, got %d", recorder.Code)
+	}
+}
+
+// assertFailed finds the email stored for the recipient and checks it was recorded as FAILED
+func assertFailed(t *testing.T, h *harness, recipient string, failed *email) {
+	t.Helper()
+
+	var listed []email
+	h.do(http.MethodGet, "/api/v1/email", nil).expect(t, http.StatusOK).decode(t, &listed)
+	for _, stored := range listed {
+		if stored.Recipient == recipient {
+			if stored.Status != "FAILED" || stored.StatusReason == "" {
+				t.Fatalf("expected a FAILED email with a reason, got %+v", stored)
+			}
+			*failed = stored
+			return
+		}
+	}
+	t.Fatalf("no email stored for %s", recipient)
+}
+
+func TestUnknownThrottleSourcesFailAtStartup(t *testing.T) {
+	cfg := &config.Config{Throttle: config.ThrottleConfig{DuplicateWindows: config.DurationMap{"RESET_PASWORD": time.Minute}}}
+
+	if _, err := app.New(cfg, app.Options{}); err == nil || !strings.Contains(err.Error(), "RESET_PASWORD") {
+		t.Fatalf("expected the misspelt source to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/farhan-nahid/email-service/app"
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/farhan-nahid/email-service/initializers"
+	"github.com/gin-gonic/gin"
+)
+
+const adminKey = "e2e-admin-key"
+
+// harness is one isolated instance of the service with its own SQLite database and SMTP server
+type harness struct {
+	t    *testing.T
+	app  *app.App
+	smtp *smtpServer
+	logs *logBuffer
+}
+
+// logBuffer collects the JSON log lines of one instance
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// response is the JSON envelope written by utils.SuccessResponse and utils.ErrorResponse
+type response struct {
+	Status  int
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Error   string          `json:"error"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// newHarness starts an instance, the overrides adjust the loaded configuration
+func newHarness(t *testing.T, overrides ...func(cfg *config.Config)) *harness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	smtp := startSMTPServer(t)
+
+	// The environment takes precedence over the defaults, so the suite does not depend on a
+	// local .env file
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "email-service.db"))
+	t.Setenv("TEMPLATE_DIR", "testdata/templates")
+	t.Setenv("SMTP_HOST", "127.0.0.1")
+	t.Setenv("SMTP_PORT", strconv.Itoa(smtp.Port()))
+	t.Setenv("SMTP_AUTH_MECHANISM", "NONE")
+	t.Setenv("EMAIL_FROM", "noreply@example.com")
+	t.Setenv("ADMIN_API_KEY", adminKey)
+	t.Setenv("ENCRYPTION_KEY", "ZTJlLWVuY3J5cHRpb24ta2V5LW9mLTMyLWJ5dGVzISE=")
+	t.Setenv("API_RATE_LIMIT_RPS", "0")
+	t.Setenv("LOG_LEVEL", "error")
+
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+	for _, override := range overrides {
+		override(cfg)
+	}
+
+	db, err := initializers.OpenDatabase(cfg.Database)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	if err := initializers.MigrateDatabase(context.Background(), db, cfg); err != nil {
+		t.Fatalf("migrating database: %v", err)
+	}
+
+	logs := &logBuffer{}
+	logger := slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	service, err := app.New(cfg, app.Options{DB: db, Logger: logger})
+	if err != nil {
+		t.Fatalf("creating app: %v", err)
+	}
+	t.Cleanup(func() {
+		service.Close()
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return &harness{t: t, app: service, smtp: smtp, logs: logs}
+}
+
+// do sends the request as the admin and decodes the response envelope
+func (h *harness) do(method, path string, body interface{}) response {
+	h.t.Helper()
+	return h.doAs(adminKey, method, path, body)
+}
+
+// doAs sends the request with the API key and decodes the response envelope
+func (h *harness) doAs(key, method, path string, body interface{}) response {
+	h.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatalf("encoding request: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	request := httptest.NewRequest(method, path, reader)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-API-Key", key)
+	recorder := httptest.NewRecorder()
+	h.app.Router().ServeHTTP(recorder, request)
+
+	result := response{Status: recorder.Code}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		h.t.Fatalf("%s %s returned %d with a body that is not JSON: %s", method, path, recorder.Code, recorder.Body.String())
+	}
+	return result
+}
+
+// serve sends the request as the admin and returns the raw response
+func (h *harness) serve(request *http.Request) *httptest.ResponseRecorder {
+	request.Header.Set("X-API-Key", adminKey)
+
+	recorder := httptest.NewRecorder()
+	h.app.Router().ServeHTTP(recorder, request)
+	return recorder
+}
+
+// companyKey creates an API key limited to the company and scopes
+func (h *harness) companyKey(company string, scopes ...string) string {
+	h.t.Helper()
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	h.do(http.MethodPost, "/api/v1/api-key", map[string]interface{}{
+		"name":         "company key",
+		"company_uuid": company,
+		"scopes":       scopes,
+	}).expect(h.t, http.StatusCreated).decode(h.t, &created)
+	return created.Key
+}
+
+// expect fails the test unless the response has the status
+func (r response) expect(t *testing.T, status int) response {
+	t.Helper()
+	if r.Status != status {
+		t.Fatalf("expected status %d, got %d: %s %s", status, r.Status, r.Message, r.Error)
+	}
+	return r
+}
+
+// decode unmarshals the data of the response
+func (r response) decode(t *testing.T, value interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.Data, value); err != nil {
+		t.Fatalf("decoding response data %s: %v", r.Data, err)
+	}
+}
@@ -0,0 +1,69 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/farhan-nahid/email-service/config"
+)
+
+// closedPort returns a local port nothing listens on
+func closedPort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestReadinessOnlyChecksDefaultSMTP(t *testing.T) {
+	h := newHarness(t)
+	h.do(http.MethodPost, "/api/v1/smtp-profile", map[string]interface{}{
+		"name": "broken", "host": "127.0.0.1", "port": closedPort(t), "tls_mode": "NONE",
+	}).expect(t, http.StatusCreated)
+
+	ready := h.serve(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if ready.Code != http.StatusOK {
+		t.Fatalf("an unreachable profile must not fail readiness, got %d: %s", ready.Code, ready.Body.String())
+	}
+
+	var statuses map[string]struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	h.do(http.MethodGet, "/api/v1/smtp-profile/status", nil).expect(t, http.StatusOK).decode(t, &statuses)
+	if broken := statuses["broken"]; broken.Status != "fail" || broken.Error != "connection refused" {
+		t.Fatalf("unexpected profile status %+v", statuses)
+	}
+
+	key := h.companyKey(companyUUID, "email:send", "email:read")
+	h.doAs(key, http.MethodGet, "/api/v1/smtp-profile/status", nil).expect(t, http.StatusForbidden)
+}
+
+func TestReadinessHidesSMTPErrors(t *testing.T) {
+	port := closedPort(t)
+	h := newHarness(t, func(cfg *config.Config) { cfg.SMTP.Port = port })
+
+	ready := h.serve(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if ready.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected an unreachable SMTP server to fail readiness, got %d", ready.Code)
+	}
+
+	var body struct {
+		Data map[string]struct {
+			Error string `json:"error"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(ready.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if smtp := body.Data["smtp"].Error; smtp != "smtp server is unreachable: connection refused" || strings.Contains(ready.Body.String(), "127.0.0.1") {
+		t.Fatalf("readiness leaks the SMTP error: %s", ready.Body.String())
+	}
+}
@@ -0,0 +1,119 @@
+package e2e
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/farhan-nahid/email-service/config"
+)
+
+// mailboxMessage holds the fields of the mailbox API the suite asserts on
+type mailboxMessage struct {
+	ID          string              `json:"id"`
+	EnvelopeTo  []string            `json:"envelope_to"`
+	Subject     string              `json:"subject"`
+	Headers     map[string][]string `json:"headers"`
+	HTML        string              `json:"html"`
+	Attachments []struct {
+		Filename    string `json:"filename"`
+		ContentType string `json:"content_type"`
+		Size        int    `json:"size"`
+	} `json:"attachments"`
+}
+
+// useMailbox captures emails in the development mailbox, without an SMTP server
+func useMailbox(cfg *config.Config) {
+	cfg.Email.Transport = "mailbox"
+	cfg.SMTP.Host = ""
+}
+
+func TestDevMailbox(t *testing.T) {
+	invoice := []byte("%PDF-1.4\n% fake invoice\n")
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(invoice)
+	}))
+	defer files.Close()
+
+	h := newHarness(t, useMailbox)
+	h.do(http.MethodPost, "/api/v1/email", newEmail("MYE", "SUBSCRIPTION_CREATED", "bob@example.com",
+		`{"plan":"Pro","invoiceLink":"`+files.URL+`/invoice.pdf"}`)).expect(t, http.StatusCreated)
+	if len(h.smtp.Messages()) != 0 {
+		t.Fatal("the mailbox must not deliver emails")
+	}
+
+	// JSON API
+	var messages []mailboxMessage
+	h.do(http.MethodGet, "/dev/mailbox/messages", nil).expect(t, http.StatusOK).decode(t, &messages)
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 captured message, got %d", len(messages))
+	}
+	message := messages[0]
+	if message.Subject != "Hello from MYE" || len(message.EnvelopeTo) != 1 || message.EnvelopeTo[0] != "bob@example.com" {
+		t.Fatalf("unexpected captured message %+v", message)
+	}
+	if !strings.Contains(message.HTML, "Plan: Pro") {
+		t.Errorf("captured HTML is not rendered: %s", message.HTML)
+	}
+	if len(message.Headers["Message-Id"]) != 1 {
+		t.Errorf("captured headers are missing the Message-ID: %v", message.Headers)
+	}
+	if len(message.Attachments) != 1 || message.Attachments[0].Filename != "invoice.pdf" || message.Attachments[0].Size != len(invoice) {
+		t.Fatalf("unexpected attachments %+v", message.Attachments)
+	}
+	h.do(http.MethodGet, "/dev/mailbox/messages/"+message.ID, nil).expect(t, http.StatusOK)
+	h.do(http.MethodGet, "/dev/mailbox/messages/unknown", nil).expect(t, http.StatusNotFound)
+
+	// Bodies, raw message and attachments
+	html := h.serve(httptest.NewRequest(http.MethodGet, "/dev/mailbox/messages/"+message.ID+"/html", nil))
+	if html.Code != http.StatusOK || !strings.Contains(html.Body.String(), "Thanks for subscribing, Bob") {
+		t.Errorf("unexpected HTML body %d: %s", html.Code, html.Body.String())
+	}
+	if html.Header().Get("Content-Security-Policy") != "sandbox" {
+		t.Error("the HTML body must be served sandboxed")
+	}
+
+	raw := h.serve(httptest.NewRequest(http.MethodGet, "/dev/mailbox/messages/"+message.ID+"/raw", nil))
+	if raw.Code != http.StatusOK {
+		t.Fatalf("unexpected raw message status %d", raw.Code)
+	}
+	if _, ok := parseMessage(t, raw.Body.Bytes()).Attachment("invoice.pdf"); !ok {
+		t.Error("raw message has no invoice.pdf attachment")
+	}
+
+	attachment := h.serve(httptest.NewRequest(http.MethodGet, "/dev/mailbox/messages/"+message.ID+"/attachments/0", nil))
+	if attachment.Code != http.StatusOK || !bytes.Equal(attachment.Body.Bytes(), invoice) {
+		t.Errorf("unexpected attachment %d: %q", attachment.Code, attachment.Body.Bytes())
+	}
+
+	// Web UI
+	page := h.serve(httptest.NewRequest(http.MethodGet, "/dev/mailbox", nil))
+	if page.Code != http.StatusOK || !strings.Contains(page.Body.String(), "Hello from MYE") || !strings.Contains(page.Body.String(), "invoice.pdf") {
+		t.Errorf("unexpected mailbox page %d: %s", page.Code, page.Body.String())
+	}
+
+	// The mailbox replaces the SMTP readiness check
+	ready := h.serve(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if ready.Code != http.StatusOK {
+		t.Errorf("expected the service to be ready without an SMTP server, got %d: %s", ready.Code, ready.Body.String())
+	}
+
+	// Clear
+	h.do(http.MethodDelete, "/dev/mailbox/messages", nil).expect(t, http.StatusOK)
+	h.do(http.MethodGet, "/dev/mailbox/messages", nil).expect(t, http.StatusOK).decode(t, &messages)
+	if len(messages) != 0 {
+		t.Fatalf("expected an empty mailbox, got %d messages", len(messages))
+	}
+}
+
+func TestDevMailboxDisabledWithSMTP(t *testing.T) {
+	h := newHarness(t)
+
+	recorder := h.serve(httptest.NewRequest(http.MethodGet, "/dev/mailbox/messages", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("the mailbox must only be served with the mailbox transport, got %d", recorder.Code)
+	}
+}
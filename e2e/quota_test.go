@@ -0,0 +1,62 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/farhan-nahid/email-service/config"
+)
+
+func TestSendingQuotaHeaders(t *testing.T) {
+	h := newHarness(t, func(cfg *config.Config) {
+		cfg.Quota.Company = config.QuotaLimits{Hourly: 2, Daily: 10}
+	})
+
+	send := func(recipient string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(newEmail("IK", "TRIAL_CREATED", recipient, `{}`))
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/email", bytes.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		return h.serve(request)
+	}
+
+	nextHour := time.Now().UTC().Truncate(time.Hour).Add(time.Hour).Unix()
+	tests := []struct {
+		status    int
+		remaining string
+	}{
+		{http.StatusCreated, "1"},
+		{http.StatusCreated, "0"},
+		{http.StatusTooManyRequests, "0"},
+	}
+
+	for i, test := range tests {
+		recorder := send(fmt.Sprintf("user%d@example.com", i))
+		if recorder.Code != test.status {
+			t.Fatalf("email %d: expected status %d, got %d: %s", i, test.status, recorder.Code, recorder.Body.String())
+		}
+
+		headers := recorder.Header()
+		if headers.Get("X-Quota-Limit") != "2" || headers.Get("X-Quota-Remaining") != test.remaining ||
+			headers.Get("X-Quota-Scope") != "company/hour" || headers.Get("X-Quota-Reset") != strconv.FormatInt(nextHour, 10) {
+			t.Fatalf("email %d: unexpected quota headers %v", i, headers)
+		}
+
+		retryAfter, _ := strconv.Atoi(headers.Get("Retry-After"))
+		if test.status == http.StatusTooManyRequests && (retryAfter < 1 || retryAfter > 3601) {
+			t.Fatalf("email %d: expected Retry-After until the next hour, got %q", i, headers.Get("Retry-After"))
+		}
+		if test.status != http.StatusTooManyRequests && headers.Get("Retry-After") != "" {
+			t.Fatalf("email %d: unexpected Retry-After %q", i, headers.Get("Retry-After"))
+		}
+	}
+
+	if len(h.smtp.Messages()) != 2 {
+		t.Fatalf("expected the rejected email not to be sent, got %d messages", len(h.smtp.Messages()))
+	}
+}
@@ -0,0 +1,152 @@
+package e2e
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/farhan-nahid/email-service/initializers"
+)
+
+func TestSeedingRequiresASender(t *testing.T) {
+	cfg := &config.Config{Database: config.DatabaseConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "seed.db")}}
+
+	db, err := initializers.OpenDatabase(cfg.Database)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	err = initializers.MigrateDatabase(context.Background(), db, cfg)
+	if err == nil || !strings.Contains(err.Error(), "EMAIL_FROM") {
+		t.Fatalf("expected seeding without EMAIL_FROM to fail, got %v", err)
+	}
+
+	cfg.Email.From = "noreply@example.com"
+	if err := initializers.MigrateDatabase(context.Background(), db, cfg); err != nil {
+		t.Fatalf("seeding with EMAIL_FROM: %v", err)
+	}
+
+	// Registered websites do not need the sender anymore
+	cfg.Email.From = ""
+	if err := initializers.MigrateDatabase(context.Background(), db, cfg); err != nil {
+		t.Fatalf("migrating a seeded database: %v", err)
+	}
+}
+
+func TestDeletedWebsiteCodesCanBeReused(t *testing.T) {
+	h := newHarness(t)
+	website := map[string]interface{}{"code": "SHOP", "display_name": "Shop", "default_sender": "shop@example.com"}
+
+	h.do(http.MethodPost, "/api/v1/website", website).expect(t, http.StatusCreated)
+	h.do(http.MethodPost, "/api/v1/website", website).expect(t, http.StatusConflict)
+	h.do(http.MethodDelete, "/api/v1/website/SHOP", nil).expect(t, http.StatusOK)
+	h.do(http.MethodPost, "/api/v1/website", website).expect(t, http.StatusCreated)
+}
+
+func TestSMTPProfilesInUseCannotBeDeleted(t *testing.T) {
+	h := newHarness(t)
+	profile := map[string]interface{}{"name": "bulk", "host": "127.0.0.1", "port": h.smtp.Port(), "tls_mode": "NONE", "auth_mechanism": "NONE"}
+
+	h.do(http.MethodPost, "/api/v1/smtp-profile", profile).expect(t, http.StatusCreated)
+	h.do(http.MethodPost, "/api/v1/website", map[string]interface{}{
+		"code": "SHOP", "display_name": "Shop", "default_sender": "shop@example.com", "smtp_profile": "bulk",
+	}).expect(t, http.StatusCreated)
+	h.do(http.MethodPost, "/api/v1/company-smtp-profile", map[string]interface{}{
+		"company_uuid": companyUUID, "profile_name": "bulk",
+	}).expect(t, http.StatusCreated)
+
+	h.do(http.MethodDelete, "/api/v1/smtp-profile/bulk", nil).expect(t, http.StatusConflict)
+	h.do(http.MethodDelete, "/api/v1/website/SHOP", nil).expect(t, http.StatusOK)
+	h.do(http.MethodDelete, "/api/v1/smtp-profile/bulk", nil).expect(t, http.StatusConflict)
+	h.do(http.MethodDelete, "/api/v1/company-smtp-profile/"+companyUUID, nil).expect(t, http.StatusOK)
+
+	h.do(http.MethodDelete, "/api/v1/smtp-profile/bulk", nil).expect(t, http.StatusOK)
+	h.do(http.MethodPost, "/api/v1/smtp-profile", profile).expect(t, http.StatusCreated)
+}
+
+func TestDKIMKeysCanBeRotatedByDeleting(t *testing.T) {
+	h := newHarness(t)
+
+	newKey := func() string {
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("generating key: %v", err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(private)
+		if err != nil {
+			t.Fatalf("encoding key: %v", err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	}
+
+	h.do(http.MethodPost, "/api/v1/dkim-key", map[string]interface{}{
+		"domain": "example.com", "selector": "2025", "private_key": newKey(),
+	}).expect(t, http.StatusCreated)
+	h.do(http.MethodDelete, "/api/v1/dkim-key/example.com", nil).expect(t, http.StatusOK)
+	h.do(http.MethodPost, "/api/v1/dkim-key", map[string]interface{}{
+		"domain": "example.com", "selector": "2026", "private_key": newKey(),
+	}).expect(t, http.StatusCreated)
+
+	var keys []struct {
+		Selector string `json:"selector"`
+	}
+	h.do(http.MethodGet, "/api/v1/dkim-key", nil).expect(t, http.StatusOK).decode(t, &keys)
+	if len(keys) != 1 || keys[0].Selector != "2026" {
+		t.Fatalf("expected only the rotated key, got %+v", keys)
+	}
+}
+
+func TestUpdateWebsiteClearsOptionalFields(t *testing.T) {
+	h := newHarness(t)
+	h.do(http.MethodPost, "/api/v1/smtp-profile", map[string]interface{}{
+		"name": "bulk", "host": "127.0.0.1", "port": h.smtp.Port(), "tls_mode": "NONE", "auth_mechanism": "NONE",
+	}).expect(t, http.StatusCreated)
+
+	h.do(http.MethodPatch, "/api/v1/website/IK", map[string]interface{}{"smtp_profile": "missing"}).
+		expect(t, http.StatusBadRequest)
+	h.do(http.MethodPatch, "/api/v1/website/IK", map[string]interface{}{
+		"reply_to": "support@example.com", "logo_url": "https://example.com/logo.png", "primary_color": "#112233",
+		"footer_text": "Inventory Keeper Ltd", "smtp_profile": "bulk",
+	}).expect(t, http.StatusOK)
+
+	type website struct {
+		DisplayName  string `json:"display_name"`
+		ReplyTo      string `json:"reply_to"`
+		LogoURL      string `json:"logo_url"`
+		PrimaryColor string `json:"primary_color"`
+		FooterText   string `json:"footer_text"`
+		SMTPProfile  string `json:"smtp_profile"`
+	}
+
+	// Omitted fields are kept
+	var updated website
+	h.do(http.MethodPatch, "/api/v1/website/IK", map[string]interface{}{"display_name": "Inventory"}).
+		expect(t, http.StatusOK).decode(t, &updated)
+	if updated != (website{"Inventory", "support@example.com", "https://example.com/logo.png", "#112233", "Inventory Keeper Ltd", "bulk"}) {
+		t.Fatalf("an update changed omitted fields: %+v", updated)
+	}
+
+	// Empty values clear them
+	var cleared website
+	h.do(http.MethodPatch, "/api/v1/website/IK", map[string]interface{}{
+		"reply_to": "", "logo_url": "", "primary_color": "", "footer_text": "", "smtp_profile": "",
+	}).expect(t, http.StatusOK).decode(t, &cleared)
+	if cleared != (website{DisplayName: "Inventory"}) {
+		t.Fatalf("expected the optional fields to be cleared: %+v", cleared)
+	}
+
+	// The profile is no longer used by the website
+	h.do(http.MethodDelete, "/api/v1/smtp-profile/bulk", nil).expect(t, http.StatusOK)
+}
@@ -0,0 +1,48 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/farhan-nahid/email-service/utils"
+)
+
+func TestSMTPPoolRetriesAfterShutdownReply(t *testing.T) {
+	h := newHarness(t)
+	h.do(http.MethodPost, "/api/v1/email", newEmail("IK", "TRIAL_CREATED", "bob@example.com", `{}`)).
+		expect(t, http.StatusCreated)
+
+	// The pooled session is closed by the server with 421, the email goes out on a new one
+	h.smtp.ShutdownNext()
+	h.do(http.MethodPost, "/api/v1/email", newEmail("IK", "TRIAL_CREATED", "alice@example.com", `{}`)).
+		expect(t, http.StatusCreated)
+	if len(h.smtp.Messages()) != 2 {
+		t.Fatalf("expected 2 delivered messages, got %d", len(h.smtp.Messages()))
+	}
+
+	var stats utils.PoolStats
+	h.do(http.MethodGet, "/api/v1/smtp-pool/stats", nil).expect(t, http.StatusOK).decode(t, &stats)
+	if stats.Reconnects != 1 || stats.Failures != 0 {
+		t.Fatalf("expected one reconnect and no failures, got %+v", stats)
+	}
+}
+
+func TestSMTPPoolChecksIdleSessions(t *testing.T) {
+	h := newHarness(t)
+	h.do(http.MethodPost, "/api/v1/email", newEmail("IK", "TRIAL_CREATED", "bob@example.com", `{}`)).
+		expect(t, http.StatusCreated)
+
+	// RSET finds the dropped session before a message is written to it
+	h.smtp.DropConnections()
+	h.do(http.MethodPost, "/api/v1/email", newEmail("IK", "TRIAL_CREATED", "alice@example.com", `{}`)).
+		expect(t, http.StatusCreated)
+	if len(h.smtp.Messages()) != 2 {
+		t.Fatalf("expected 2 delivered messages, got %d", len(h.smtp.Messages()))
+	}
+
+	var stats utils.PoolStats
+	h.do(http.MethodGet, "/api/v1/smtp-pool/stats", nil).expect(t, http.StatusOK).decode(t, &stats)
+	if stats.Stale != 1 || stats.Reconnects != 0 || stats.Dials != 2 {
+		t.Fatalf("expected the stale session to be replaced before sending, got %+v", stats)
+	}
+}
@@ -0,0 +1,275 @@
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ------------------- Fake SMTP Server ------------------- //
+
+// receivedMessage is one message accepted by the fake server
+type receivedMessage struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// smtpServer is a minimal SMTP server that keeps every accepted message in memory. Recipients
+// listed in rejectRecipients are refused with a 550 reply
+type smtpServer struct {
+	listener net.Listener
+
+	mu               sync.Mutex
+	messages         []receivedMessage
+	rejectRecipients map[string]bool
+	conns            map[net.Conn]bool
+	shutdownNext     bool
+}
+
+// startSMTPServer listens on a random local port until the test ends
+func startSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("starting smtp server: %v", err)
+	}
+
+	server := &smtpServer{listener: listener, rejectRecipients: make(map[string]bool), conns: make(map[net.Conn]bool)}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *smtpServer) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Reject makes the server refuse the recipient
+func (s *smtpServer) Reject(recipient string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejectRecipients[recipient] = true
+}
+
+// ShutdownNext answers the next MAIL command with 421 and closes that connection
+func (s *smtpServer) ShutdownNext() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shutdownNext = true
+}
+
+// DropConnections closes every open connection without a reply, like a server timing out
+// idle sessions
+func (s *smtpServer) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// Messages returns the messages accepted so far
+func (s *smtpServer) Messages() []receivedMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMessage(nil), s.messages...)
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	s.mu.Lock()
+	s.conns[conn] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	text := textproto.NewConn(conn)
+	reply := func(format string, args ...interface{}) bool {
+		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		return text.PrintfLine(format, args...) == nil
+	}
+
+	var current receivedMessage
+	reply("220 localhost fake smtp ready")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, argument, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case "HELO", "NOOP":
+			reply("250 OK")
+		case "RSET":
+			current = receivedMessage{}
+			reply("250 OK")
+		case "MAIL":
+			s.mu.Lock()
+			shutdown := s.shutdownNext
+			s.shutdownNext = false
+			s.mu.Unlock()
+			if shutdown {
+				reply("421 4.3.2 service shutting down")
+				return
+			}
+			current = receivedMessage{From: envelopeAddress(argument)}
+			reply("250 OK")
+		case "RCPT":
+			recipient := envelopeAddress(argument)
+			s.mu.Lock()
+			rejected := s.rejectRecipients[recipient]
+			s.mu.Unlock()
+			if rejected {
+				reply("550 5.1.1 mailbox %s unavailable", recipient)
+				continue
+			}
+			current.To = append(current.To, recipient)
+			reply("250 OK")
+		case "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			current.Data = data
+			s.mu.Lock()
+			s.messages = append(s.messages, current)
+			s.mu.Unlock()
+			current = receivedMessage{}
+			reply("250 OK queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+// envelopeAddress extracts the address of "FROM:<a@b>" and "TO:<a@b>" arguments
+func envelopeAddress(argument string) string {
+	_, address, _ := strings.Cut(argument, ":")
+	address, _, _ = strings.Cut(strings.TrimSpace(address), " ")
+	return strings.Trim(address, "<>")
+}
+
+// ------------------- MIME ------------------- //
+
+// mimePart is a decoded leaf part of a received message
+type mimePart struct {
+	ContentType string
+	Filename    string
+	Body        []byte
+}
+
+// parsedMessage is a received message with its headers and decoded parts
+type parsedMessage struct {
+	Header mail.Header
+	Parts  []mimePart
+}
+
+// Part returns the first part with the media type
+func (m parsedMessage) Part(mediaType string) (mimePart, bool) {
+	for _, part := range m.Parts {
+		if part.ContentType == mediaType {
+			return part, true
+		}
+	}
+	return mimePart{}, false
+}
+
+// Attachment returns the part sent with the file name
+func (m parsedMessage) Attachment(filename string) (mimePart, bool) {
+	for _, part := range m.Parts {
+		if part.Filename == filename {
+			return part, true
+		}
+	}
+	return mimePart{}, false
+}
+
+// parseMessage decodes the headers and walks every multipart level of the message
+func parseMessage(t *testing.T, data []byte) parsedMessage {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("parsing received message: %v", err)
+	}
+
+	parsed := parsedMessage{Header: msg.Header}
+	if err := collectParts(&parsed, textproto.MIMEHeader(msg.Header), msg.Body); err != nil {
+		t.Fatalf("parsing received message body: %v", err)
+	}
+	return parsed
+}
+
+func collectParts(parsed *parsedMessage, header textproto.MIMEHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := collectParts(parsed, part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	var filename string
+	if _, dispositionParams, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		filename = dispositionParams["filename"]
+	}
+	parsed.Parts = append(parsed.Parts, mimePart{ContentType: mediaType, Filename: filename, Body: content})
+	return nil
+}
@@ -0,0 +1,25 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestWebhookSubscriptionsRejectInternalURLs(t *testing.T) {
+	h := newHarness(t)
+	subscription := func(url string) map[string]interface{} {
+		return map[string]interface{}{"url": url, "secret": "subscriber-secret-value"}
+	}
+
+	for _, url := range []string{
+		"http://93.184.215.14/hooks/email",
+		"https://127.0.0.1:8080/hooks/email",
+		"https://localhost/hooks/email",
+		"https://169.254.169.254/latest/meta-data",
+		"https://10.1.2.3/hooks/email",
+	} {
+		h.do(http.MethodPost, "/api/v1/webhook-subscription", subscription(url)).expect(t, http.StatusBadRequest)
+	}
+	h.do(http.MethodPost, "/api/v1/webhook-subscription", subscription("https://93.184.215.14/hooks/email")).
+		expect(t, http.StatusCreated)
+}
@@ -2,36 +2,50 @@ package initializers
 
 import (
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 
+	"github.com/farhan-nahid/email-service/config"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-var DB *gorm.DB
-
-func ConnectToDatabase() {
-	dbUser 	   := os.Getenv("DB_USER") 
-	dbPassword := os.Getenv("DB_PASS")
-	dbHost 	   := os.Getenv("DB_HOST")
-	DBName     := os.Getenv("DB_NAME")
-	dbPort     := os.Getenv("DB_PORT")
+// OpenDatabase connects to Postgres, or opens the SQLite database when DB_DRIVER is sqlite,
+// and registers the metrics and tracing callbacks
+func OpenDatabase(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	if cfg.Driver == "sqlite" {
+		slog.Info("Attempting to open sqlite db", slog.String("path", cfg.Path))
+		dialector = sqlite.Open(SQLiteDSN(cfg.Path))
+	} else {
+		slog.Info("Attempting to connect to db", slog.String("host", cfg.Host), slog.String("database", cfg.Name))
+		dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d", cfg.Host, cfg.User, cfg.Password, cfg.Name, cfg.Port)
+		if cfg.SSLMode != "" {
+			dsn += " sslmode=" + cfg.SSLMode
+		}
+		dialector = postgres.Open(dsn)
+	}
 
-	log.Println("Attempting to connect to db")
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s", dbHost, dbUser, dbPassword, DBName, dbPort)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger:                 logger.Default.LogMode(logger.Silent),
 		SkipDefaultTransaction: true,
 	})
-	
 	if err != nil {
-		log.Println(err.Error())
-		panic("Failed to Connect Database !")
-	} else {
-		log.Println("Database Connected Successfully !")
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	slog.Info("Database Connected Successfully !")
 
-	DB = db
+	registerDBMetrics(db)
+	registerDBTracing(db)
+	return db, nil
+}
+
+// SQLiteDSN enables foreign keys and a busy timeout, an in memory database is shared by
+// every connection of the pool
+func SQLiteDSN(path string) string {
+	if path == ":memory:" {
+		return "file::memory:?cache=shared&_foreign_keys=on&_busy_timeout=5000"
+	}
+	return "file:" + path + "?_foreign_keys=on&_busy_timeout=5000"
 }
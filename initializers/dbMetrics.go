@@ -0,0 +1,27 @@
+package initializers
+
+import (
+	"errors"
+
+	"github.com/farhan-nahid/email-service/utils"
+	"gorm.io/gorm"
+)
+
+// registerDBMetrics counts failed queries per operation
+func registerDBMetrics(db *gorm.DB) {
+	count := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+				utils.DBErrors.WithLabelValues(operation).Inc()
+			}
+		}
+	}
+
+	callbacks := db.Callback()
+	callbacks.Create().After("gorm:create").Register("metrics:create", count("create"))
+	callbacks.Query().After("gorm:query").Register("metrics:query", count("query"))
+	callbacks.Update().After("gorm:update").Register("metrics:update", count("update"))
+	callbacks.Delete().After("gorm:delete").Register("metrics:delete", count("delete"))
+	callbacks.Row().After("gorm:row").Register("metrics:row", count("row"))
+	callbacks.Raw().After("gorm:raw").Register("metrics:raw", count("raw"))
+}
@@ -0,0 +1,71 @@
+package initializers
+
+import (
+	"github.com/farhan-nahid/email-service/utils"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanInstanceKey = "otel:span"
+
+// dbSystem names the database behind a gorm dialector the way the semantic conventions do
+func dbSystem(dialector gorm.Dialector) attribute.KeyValue {
+	switch dialector.Name() {
+	case "postgres":
+		return semconv.DBSystemNamePostgreSQL
+	case "sqlite":
+		return semconv.DBSystemNameSQLite
+	default:
+		return semconv.DBSystemNameKey.String(dialector.Name())
+	}
+}
+
+// registerDBTracing wraps every query in a span that is a child of the statement context,
+// queries only join the request trace when they are run with WithContext
+func registerDBTracing(db *gorm.DB) {
+	system := dbSystem(db.Dialector)
+	start := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			_, span := utils.StartSpan(tx.Statement.Context, "gorm."+operation,
+				system,
+				semconv.DBOperationName(operation),
+			)
+			tx.InstanceSet(spanInstanceKey, span)
+		}
+	}
+
+	end := func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet(spanInstanceKey)
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		span.SetAttributes(
+			semconv.DBCollectionName(tx.Statement.Table),
+			semconv.DBQueryText(tx.Statement.SQL.String()),
+			semconv.DBResponseReturnedRows(int(tx.Statement.RowsAffected)),
+		)
+
+		err := tx.Error
+		if err == gorm.ErrRecordNotFound {
+			err = nil
+		}
+		utils.EndSpan(span, err)
+	}
+
+	callbacks := db.Callback()
+	callbacks.Create().Before("gorm:create").Register("tracing:before_create", start("create"))
+	callbacks.Create().After("gorm:create").Register("tracing:after_create", end)
+	callbacks.Query().Before("gorm:query").Register("tracing:before_query", start("query"))
+	callbacks.Query().After("gorm:query").Register("tracing:after_query", end)
+	callbacks.Update().Before("gorm:update").Register("tracing:before_update", start("update"))
+	callbacks.Update().After("gorm:update").Register("tracing:after_update", end)
+	callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", start("delete"))
+	callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", end)
+	callbacks.Row().Before("gorm:row").Register("tracing:before_row", start("row"))
+	callbacks.Row().After("gorm:row").Register("tracing:after_row", end)
+	callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", start("raw"))
+	callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", end)
+}
@@ -0,0 +1,50 @@
+package initializers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/farhan-nahid/email-service/migration"
+	"github.com/farhan-nahid/email-service/models"
+	"gorm.io/gorm"
+)
+
+// MigrateDatabase applies pending migrations when DB_MIGRATE_ON_START is set, otherwise it
+// only warns when the schema is behind the binary. SQLite databases are always migrated
+func MigrateDatabase(ctx context.Context, db *gorm.DB, cfg *config.Config) error {
+	// SQLite is only used for tests and single binary mode, its schema always follows the models
+	if cfg.Database.Driver == "sqlite" {
+		if err := migration.AutoMigrate(db.WithContext(ctx)); err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+		return seedWebsites(ctx, db, cfg)
+	}
+
+	migrator, err := migration.New(db)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	if !cfg.Database.MigrateOnStart {
+		if pending, err := migrator.Pending(ctx); err != nil {
+			slog.Warn("Failed to check migrations", slog.Any("error", err))
+		} else if pending > 0 {
+			slog.Warn("Database has pending migrations, run `go run ./cmd/migrate up`", slog.Int("pending", pending))
+		}
+		return nil
+	}
+
+	if err := migrator.Up(ctx); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return seedWebsites(ctx, db, cfg)
+}
+
+func seedWebsites(ctx context.Context, db *gorm.DB, cfg *config.Config) error {
+	if err := migration.SeedWebsites(db.WithContext(ctx), models.EmailAddress(cfg.Email.From)); err != nil {
+		return fmt.Errorf("failed to seed websites: %w", err)
+	}
+	return nil
+}
@@ -2,63 +2,68 @@ package main
 
 import (
 	"context"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
 	"os/signal"
 	"time"
 
+	"github.com/farhan-nahid/email-service/app"
+	"github.com/farhan-nahid/email-service/config"
 	"github.com/farhan-nahid/email-service/initializers"
-	"github.com/farhan-nahid/email-service/routes"
 	"github.com/farhan-nahid/email-service/utils"
-	"github.com/gin-gonic/gin"
 )
 
-// init() runs before main(), initializing environment variables and database connection
-func init() {
-	initializers.LoadEnvVariables() // Load environment variables from the .env file or system
-	initializers.ConnectToDatabase() // Uncomment if you need database connection initialization
-}
-
 func main() {
-	// Create a new Gin router instance
-	router := gin.Default()
+	// Load the configuration file, .env and environment variables
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		slog.Error("Error loading configuration", slog.Any("error", err))
+		os.Exit(1)
+	}
 
-	router.GET("/health-check", func(c *gin.Context) {utils.SuccessResponse(c, http.StatusOK, nil, "Service is up and running")})
-	routes.EmailRoute(router) // Register email routes
+	logger := utils.NewLogger(cfg.Log)
+	slog.SetDefault(logger)
+	slog.Info("Effective configuration", slog.Any("config", cfg.Redacted()))
 
-	// Define the HTTP server configuration
-	server := &http.Server{
-		Addr:   ":" + os.Getenv("PORT"), // Server will listen on port 8080
-		Handler: router,  // Use Gin router as the handler
+	// Export traces to the collector configured through OTEL_* variables
+	shutdownTracing, err := utils.SetupTracing(context.Background(), cfg.Tracing)
+	if err != nil {
+		slog.Error("Failed to set up tracing", slog.Any("error", err))
+		os.Exit(1)
 	}
 
-	// Channel to capture OS signals for graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt) // Listen for interrupt signals (e.g., Ctrl+C)
+	// Connect to the database and create the SMTP pool and template registry
+	service, err := app.New(cfg, app.Options{Logger: logger})
+	if err != nil {
+		slog.Error("Failed to start the service", slog.Any("error", err))
+		os.Exit(1)
+	}
 
-	// Start the server in a separate goroutine
-	go func() {
-		log.Println("Starting server on port 8080...")
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			// Log fatal error if server fails to start and is not already shutting down
-			log.Fatalf("Failed to start server: %v", err)
-		}
-	}()
+	// Apply pending migrations when DB_MIGRATE_ON_START is set
+	if err := initializers.MigrateDatabase(context.Background(), service.DB, cfg); err != nil {
+		slog.Error("Failed to migrate database", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// Serve until an interrupt signal (e.g., Ctrl+C) asks for a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	// Wait for an interrupt signal
-	<-quit
-	log.Println("Shutting down server...")
+	if err := service.Run(ctx); err != nil {
+		slog.Error("Server stopped with an error", slog.Any("error", err))
+		service.Close()
+		os.Exit(1)
+	}
 
-	// Create a context with a timeout for graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel() // Ensure context cancellation to free up resources
+	// Close pooled SMTP connections and the database
+	service.Close()
 
-	// Attempt to gracefully shut down the server
-	if err := server.Shutdown(ctx); err != nil {
-		// Log fatal error if the server cannot shut down gracefully
-		log.Fatalf("Server forced to shutdown: %v", err)
+	// Flush spans that have not been exported yet
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Failed to flush traces", slog.Any("error", err))
 	}
 
-	log.Println("Server exited gracefully")
+	slog.Info("Server exited gracefully")
 }
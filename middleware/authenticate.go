@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var errUnauthorized = errors.New("missing or invalid API key")
+
+// credentials reads the key or JWT from the Authorization bearer token or the X-API-Key header
+func credentials(c *gin.Context) string {
+	if token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); found {
+		return strings.TrimSpace(token)
+	}
+	return c.GetHeader("X-API-Key")
+}
+
+// Authenticate resolves the API key or dashboard JWT of the request and stores the caller as
+// "principal", ADMIN_API_KEY is accepted as an admin key to bootstrap the first stored keys
+func Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := credentials(c)
+		if key == "" {
+			utils.ErrorResponse(c, http.StatusUnauthorized, errUnauthorized)
+			return
+		}
+
+		if adminKey := Deps(c).Config.Auth.AdminAPIKey; adminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) == 1 {
+			c.Set("principal", &models.Principal{ID: "bootstrap", Name: "bootstrap", Admin: true})
+			c.Next()
+			return
+		}
+
+		if utils.LooksLikeJWT(key) {
+			authenticateJWT(c, key)
+			return
+		}
+
+		prefix, ok := utils.APIKeyPrefix(key)
+		if !ok {
+			utils.ErrorResponse(c, http.StatusUnauthorized, errUnauthorized)
+			return
+		}
+
+		db := Deps(c).DB.WithContext(c.Request.Context())
+
+		var apiKey models.APIKey
+		if err := db.Where("prefix = ?", prefix).First(&apiKey).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusUnauthorized, errUnauthorized)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(utils.HashAPIKey(key)), []byte(apiKey.Hash)) != 1 {
+			utils.ErrorResponse(c, http.StatusUnauthorized, errUnauthorized)
+			return
+		}
+
+		now := time.Now()
+		if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(now) {
+			utils.ErrorResponse(c, http.StatusUnauthorized, errors.New("API key has expired"))
+			return
+		}
+
+		db.Model(&apiKey).UpdateColumn("last_used_at", now)
+
+		c.Set("principal", &models.Principal{
+			ID:        "key:" + apiKey.Prefix,
+			Name:      apiKey.Name,
+			Admin:     apiKey.Admin,
+			Companies: []uuid.UUID{apiKey.CompanyUUID},
+			Scopes:    apiKey.Scopes,
+		})
+		c.Next()
+	}
+}
+
+// authenticateJWT maps the roles and companies claims of a dashboard user to a principal
+func authenticateJWT(c *gin.Context, token string) {
+	cfg := Deps(c).Config.Auth
+	if !utils.JWTEnabled(cfg) {
+		utils.ErrorResponse(c, http.StatusUnauthorized, errUnauthorized)
+		return
+	}
+
+	claims, err := utils.VerifyJWT(cfg, token)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	c.Set("principal", models.PrincipalFromRoles(claims.Subject, claims.Roles, claims.Companies))
+	c.Next()
+}
+
+// RequireScope rejects callers without the scope, admins pass every scope check
+func RequireScope(scope models.APIScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal, ok := c.MustGet("principal").(*models.Principal); !ok || !principal.HasScope(scope) {
+			utils.ErrorResponse(c, http.StatusForbidden, errors.New("caller is missing the "+string(scope)+" scope"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAdmin rejects callers that are not admins
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal, ok := c.MustGet("principal").(*models.Principal); !ok || !principal.Admin {
+			utils.ErrorResponse(c, http.StatusForbidden, errors.New("admin access required"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Dependencies are the services of one app instance, handlers reach them through the
+// request context so several instances can share a process
+type Dependencies struct {
+	Config      *config.Config
+	DB          *gorm.DB
+	Mailer      *utils.Mailer
+	RateLimiter *RateLimiter
+	Logger      *slog.Logger
+}
+
+// Inject stores the dependencies in the context of every request, it must be the first
+// middleware of the router
+func Inject(deps *Dependencies) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("dependencies", deps)
+		c.Next()
+	}
+}
+
+// Deps returns the dependencies stored by Inject
+func Deps(c *gin.Context) *Dependencies {
+	return c.MustGet("dependencies").(*Dependencies)
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// RateLimiter holds one token bucket per caller, shared by every route group so the limit
+// applies to the whole API
+type RateLimiter struct {
+	mu       sync.Mutex
+	limit    rate.Limit
+	burst    int
+	limiters map[string]*callerLimiter
+	done     chan struct{}
+}
+
+type callerLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter uses API_RATE_LIMIT_RPS (default 20, 0 disables limiting) and
+// API_RATE_LIMIT_BURST (default twice the rate), Close stops forgetting idle callers
+func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	rps, burst := cfg.RPS, cfg.Burst
+	if burst <= 0 {
+		burst = max(int(math.Ceil(rps*2)), 1)
+	}
+
+	limiter := &RateLimiter{limit: rate.Limit(rps), burst: burst, limiters: make(map[string]*callerLimiter), done: make(chan struct{})}
+	go limiter.reap(10 * time.Minute)
+	return limiter
+}
+
+// Close stops the idle caller reaper
+func (l *RateLimiter) Close() {
+	close(l.done)
+}
+
+func (l *RateLimiter) get(caller string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.limiters[caller]
+	if !ok {
+		entry = &callerLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.limiters[caller] = entry
+	}
+	entry.lastSeen = time.Now()
+	return entry.limiter
+}
+
+// reap forgets callers that have been idle long enough for their bucket to be full again
+func (l *RateLimiter) reap(idle time.Duration) {
+	ticker := time.NewTicker(idle)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+		}
+
+		l.mu.Lock()
+		for caller, entry := range l.limiters {
+			if time.Since(entry.lastSeen) > idle {
+				delete(l.limiters, caller)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// RateLimit limits each authenticated caller to API_RATE_LIMIT_RPS requests per second,
+// it must run after Authenticate
+func RateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limiters := Deps(c).RateLimiter
+		if limiters == nil || limiters.limit == 0 {
+			c.Next()
+			return
+		}
+
+		principal, _ := c.MustGet("principal").(*models.Principal)
+		limiter := limiters.get(principal.ID)
+
+		c.Header("X-RateLimit-Limit", strconv.FormatFloat(float64(limiters.limit), 'f', -1, 64))
+
+		reservation := limiter.Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			reservation.Cancel()
+			c.Header("X-RateLimit-Remaining", "0")
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			utils.ErrorResponse(c, http.StatusTooManyRequests, errors.New("rate limit exceeded"))
+			return
+		}
+
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(int(limiter.Tokens())))
+		c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// requestIDPattern keeps caller supplied ids short and safe to log
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:\-]{1,128}$`)
+
+// RequestID reuses the X-Request-ID header of the caller or generates one, echoes it in the
+// response and stores it in the request context for logging
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Header("X-Request-ID", requestID)
+		c.Request = c.Request.WithContext(utils.WithRequestID(c.Request.Context(), requestID))
+		c.Next()
+	}
+}
+
+// RequestLogger writes one structured log line per request to the logger, it replaces the
+// gin logger
+func RequestLogger(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= 500 {
+			level = slog.LevelError
+		}
+
+		// Matched requests are logged by route pattern, unsubscribe and tracking paths carry
+		// signed tokens that encode recipient addresses
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		logger.Log(c.Request.Context(), level, "request completed",
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.Int("status", c.Writer.Status()),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
@@ -0,0 +1,310 @@
+package migration
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ------------------- Versioned Migrations ------------------- //
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID is the Postgres advisory lock held while migrating, so replicas starting together
+// apply each migration once
+const lockID = 4_150_218_397
+
+// baselineVersion is the migration that creates the schema, rolling it back drops every table
+const baselineVersion = 1
+
+// ErrBaselineRollback is returned when a rollback would revert the baseline without Force
+var ErrBaselineRollback = errors.New("rolling back the baseline drops every table and its data, force it to continue")
+
+// Migration is one version read from sql/<version>_<name>.up.sql and its .down.sql
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// SchemaMigration records an applied version in the schema_migrations table
+type SchemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// MigrationStatus reports whether a known version has been applied
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// Migrator applies the embedded migrations to a database
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+
+	// Force allows rollbacks that revert the baseline
+	Force bool
+}
+
+// New creates a migrator with the migrations embedded in the binary
+func New(db *gorm.DB) (*Migrator, error) {
+	migrations, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// load pairs the up and down files of each version, every version needs an up file
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), ".")
+		versionText, name, found := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionText)
+		if !ok || !found || err != nil || version <= 0 || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration file %s must be named <version>_<name>.up.sql or .down.sql", entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join("sql", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migration version %d is used by %s and %s", version, migration.Name, name)
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest returns the highest known version
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down rolls back the given number of applied migrations, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		var reverts []Migration
+		for i := len(m.migrations) - 1; i >= 0 && len(reverts) < steps; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				reverts = append(reverts, m.migrations[i])
+			}
+		}
+		return m.revertAll(conn, reverts)
+	})
+}
+
+// To migrates up or down until exactly the versions up to and including target are applied,
+// a target of 0 rolls back everything
+func (m *Migrator) To(ctx context.Context, target int) error {
+	if target != 0 && !m.known(target) {
+		return fmt.Errorf("unknown migration version %d", target)
+	}
+
+	return m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		var reverts []Migration
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok && m.migrations[i].Version > target {
+				reverts = append(reverts, m.migrations[i])
+			}
+		}
+		if err := m.revertAll(conn, reverts); err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok && migration.Version <= target {
+				if err := m.apply(conn, migration); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Status lists every known migration with the time it was applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn := m.db.WithContext(ctx)
+	applied := map[int]time.Time{}
+	if conn.Migrator().HasTable(&SchemaMigration{}) {
+		var err error
+		if applied, err = appliedVersions(conn); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending returns the number of known migrations that have not been applied
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) known(version int) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// locked runs fn on a single connection holding the advisory lock, other replicas wait for
+// it to be released and then find nothing left to apply
+func (m *Migrator) locked(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockID).Error; err != nil {
+			return fmt.Errorf("acquiring migration lock: %w", err)
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", lockID)
+
+		if err := conn.AutoMigrate(&SchemaMigration{}); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// apply runs the up file and records the version in one transaction
+func (m *Migrator) apply(conn *gorm.DB, migration Migration) error {
+	slog.Info("Applying migration", slog.Int("version", migration.Version), slog.String("name", migration.Name))
+
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(migration.Up).Error; err != nil {
+			return err
+		}
+		return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("applying migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// revertAll reverts the migrations in order, nothing is reverted when they include the
+// baseline and the rollback is not forced
+func (m *Migrator) revertAll(conn *gorm.DB, migrations []Migration) error {
+	for _, migration := range migrations {
+		if migration.Version == baselineVersion && !m.Force {
+			return ErrBaselineRollback
+		}
+	}
+
+	for _, migration := range migrations {
+		if err := m.revert(conn, migration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revert runs the down file and forgets the version in one transaction
+func (m *Migrator) revert(conn *gorm.DB, migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("migration %d_%s cannot be rolled back, it has no down file", migration.Version, migration.Name)
+	}
+	slog.Info("Reverting migration", slog.Int("version", migration.Version), slog.String("name", migration.Name))
+
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(migration.Down).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&SchemaMigration{}, "version = ?", migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func appliedVersions(conn *gorm.DB) (map[int]time.Time, error) {
+	var rows []SchemaMigration
+	if err := conn.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
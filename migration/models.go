@@ -0,0 +1,20 @@
+package migration
+
+import (
+	"github.com/farhan-nahid/email-service/models"
+	"gorm.io/gorm"
+)
+
+// Models are the tables of the service. SQLite databases are created from them because the
+// versioned SQL migrations are written for Postgres
+var Models = []interface{}{
+	&models.Email{}, &models.WebsiteConfig{}, &models.SMTPProfile{}, &models.CompanySMTPProfile{},
+	&models.DKIMKey{}, &models.Suppression{}, &models.UnsubscribePreference{}, &models.EmailEvent{},
+	&models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.APIKey{}, &models.SendingQuota{},
+	&models.WebhookToken{},
+}
+
+// AutoMigrate creates or updates the tables of a SQLite database from the models
+func AutoMigrate(db *gorm.DB) error {
+	return db.AutoMigrate(Models...)
+}
@@ -0,0 +1,31 @@
+package migration
+
+import (
+	"fmt"
+
+	"github.com/farhan-nahid/email-service/models"
+	"gorm.io/gorm"
+)
+
+// SeedWebsites adds the existing products to the website registry, websites that are
+// already registered are left unchanged. New websites are sent from defaultSender, so it
+// must be a valid address when any of them is missing
+func SeedWebsites(db *gorm.DB, defaultSender models.EmailAddress) error {
+	for _, website := range models.DefaultWebsites(defaultSender) {
+		var count int64
+		if err := db.Model(&models.WebsiteConfig{}).Where("code = ?", website.Code).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		if !defaultSender.IsValid() {
+			return fmt.Errorf("EMAIL_FROM must be a valid address to register website %s, got %q", website.Code, defaultSender)
+		}
+		if err := db.Create(&website).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS sending_quota;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS email_events;
+DROP TABLE IF EXISTS unsubscribe_preferences;
+DROP TABLE IF EXISTS suppressions;
+DROP TABLE IF EXISTS dkim_keys;
+DROP TABLE IF EXISTS company_smtp_profiles;
+DROP TABLE IF EXISTS smtp_profiles;
+DROP TABLE IF EXISTS websites;
+DROP TABLE IF EXISTS emails;
@@ -0,0 +1,214 @@
+-- Baseline of the schema previously created by AutoMigrate. Every statement is idempotent so
+-- databases that were migrated with AutoMigrate adopt it without changes. Tables created by an
+-- older release lack the columns added since, so they are added before they are indexed.
+
+CREATE TABLE IF NOT EXISTS emails (
+    id            bigserial,
+    created_at    timestamptz,
+    updated_at    timestamptz,
+    deleted_at    timestamptz,
+    uuid          text NOT NULL,
+    company_uuid  text,
+    name          text,
+    sender        text,
+    recipient     text,
+    subject       text,
+    status        text,
+    status_reason text,
+    source        text,
+    website       text,
+    payload       text,
+    message_id    text,
+    PRIMARY KEY (id, uuid),
+    CONSTRAINT uni_emails_uuid UNIQUE (uuid)
+);
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS status_reason text;
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS message_id text;
+CREATE INDEX IF NOT EXISTS idx_emails_deleted_at ON emails (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_emails_company_uuid ON emails (company_uuid);
+CREATE INDEX IF NOT EXISTS idx_emails_recipient ON emails (recipient);
+CREATE INDEX IF NOT EXISTS idx_emails_website ON emails (website);
+CREATE INDEX IF NOT EXISTS idx_emails_message_id ON emails (message_id);
+
+CREATE TABLE IF NOT EXISTS websites (
+    id              bigserial PRIMARY KEY,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    deleted_at      timestamptz,
+    code            text,
+    display_name    text,
+    default_sender  text,
+    reply_to        text,
+    logo_url        text,
+    primary_color   text,
+    footer_text     text,
+    allowed_sources text,
+    smtp_profile    text,
+    track_opens     boolean,
+    track_clicks    boolean
+);
+ALTER TABLE websites ADD COLUMN IF NOT EXISTS track_opens boolean;
+ALTER TABLE websites ADD COLUMN IF NOT EXISTS track_clicks boolean;
+CREATE INDEX IF NOT EXISTS idx_websites_deleted_at ON websites (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_websites_code ON websites (code);
+
+CREATE TABLE IF NOT EXISTS smtp_profiles (
+    id             bigserial PRIMARY KEY,
+    created_at     timestamptz,
+    updated_at     timestamptz,
+    deleted_at     timestamptz,
+    name           text,
+    host           text,
+    port           bigint,
+    tls_mode       text,
+    auth_mechanism text,
+    username       text,
+    password       text
+);
+CREATE INDEX IF NOT EXISTS idx_smtp_profiles_deleted_at ON smtp_profiles (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_smtp_profiles_name ON smtp_profiles (name);
+
+CREATE TABLE IF NOT EXISTS company_smtp_profiles (
+    id           bigserial PRIMARY KEY,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    deleted_at   timestamptz,
+    company_uuid text,
+    website      text,
+    profile_name text
+);
+CREATE INDEX IF NOT EXISTS idx_company_smtp_profiles_deleted_at ON company_smtp_profiles (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_company_smtp_profiles_company_uuid ON company_smtp_profiles (company_uuid);
+
+CREATE TABLE IF NOT EXISTS dkim_keys (
+    id          bigserial PRIMARY KEY,
+    created_at  timestamptz,
+    updated_at  timestamptz,
+    deleted_at  timestamptz,
+    domain      text,
+    selector    text,
+    private_key text
+);
+CREATE INDEX IF NOT EXISTS idx_dkim_keys_deleted_at ON dkim_keys (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dkim_keys_domain ON dkim_keys (domain);
+
+CREATE TABLE IF NOT EXISTS suppressions (
+    id           bigserial PRIMARY KEY,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    deleted_at   timestamptz,
+    address      text,
+    scope        text,
+    website      text,
+    company_uuid text,
+    reason       text,
+    expires_at   timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_suppressions_deleted_at ON suppressions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_suppressions_address ON suppressions (address);
+
+CREATE TABLE IF NOT EXISTS unsubscribe_preferences (
+    id              bigserial PRIMARY KEY,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    deleted_at      timestamptz,
+    recipient       text,
+    website         text,
+    category        text,
+    unsubscribed_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_unsubscribe_preferences_deleted_at ON unsubscribe_preferences (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_unsubscribe_preferences_recipient ON unsubscribe_preferences (recipient);
+
+CREATE TABLE IF NOT EXISTS email_events (
+    id          bigserial PRIMARY KEY,
+    created_at  timestamptz,
+    updated_at  timestamptz,
+    deleted_at  timestamptz,
+    email_uuid  text,
+    type        text,
+    provider    text,
+    recipient   text,
+    reason      text,
+    url         text,
+    user_agent  text,
+    ip_hash     text,
+    occurred_at timestamptz
+);
+ALTER TABLE email_events ADD COLUMN IF NOT EXISTS url text;
+ALTER TABLE email_events ADD COLUMN IF NOT EXISTS user_agent text;
+ALTER TABLE email_events ADD COLUMN IF NOT EXISTS ip_hash text;
+CREATE INDEX IF NOT EXISTS idx_email_events_deleted_at ON email_events (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_email_events_email_uuid ON email_events (email_uuid);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id           bigserial PRIMARY KEY,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    deleted_at   timestamptz,
+    company_uuid text,
+    website      text,
+    url          text,
+    secret       text,
+    event_types  text
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_deleted_at ON webhook_subscriptions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_company_uuid ON webhook_subscriptions (company_uuid);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               bigserial PRIMARY KEY,
+    created_at       timestamptz,
+    updated_at       timestamptz,
+    deleted_at       timestamptz,
+    event_id         text,
+    subscription_id  bigint,
+    email_uuid       text,
+    event_type       text,
+    payload          text,
+    state            text,
+    attempts         bigint,
+    next_attempt_at  timestamptz,
+    last_status_code bigint,
+    last_error       text,
+    delivered_at     timestamptz,
+    trace_parent     text
+);
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS trace_parent text;
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_deleted_at ON webhook_deliveries (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event_id ON webhook_deliveries (event_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_email_uuid ON webhook_deliveries (email_uuid);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_state ON webhook_deliveries (state);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           bigserial PRIMARY KEY,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    deleted_at   timestamptz,
+    name         text,
+    prefix       text,
+    hash         text,
+    scopes       text,
+    company_uuid text,
+    admin        boolean,
+    last_used_at timestamptz,
+    expires_at   timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_api_keys_deleted_at ON api_keys (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys (prefix);
+CREATE INDEX IF NOT EXISTS idx_api_keys_company_uuid ON api_keys (company_uuid);
+
+CREATE TABLE IF NOT EXISTS sending_quota (
+    id            bigserial PRIMARY KEY,
+    created_at    timestamptz,
+    updated_at    timestamptz,
+    deleted_at    timestamptz,
+    company_uuid  text,
+    website       text,
+    hourly_limit  bigint,
+    daily_limit   bigint,
+    monthly_limit bigint
+);
+CREATE INDEX IF NOT EXISTS idx_sending_quota_deleted_at ON sending_quota (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sending_quota_subject ON sending_quota (company_uuid, website);
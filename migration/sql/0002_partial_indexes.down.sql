@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_suppressions_active_address;
+DROP INDEX IF EXISTS idx_emails_recipient_lower_created_at;
+DROP INDEX IF EXISTS idx_webhook_deliveries_due;
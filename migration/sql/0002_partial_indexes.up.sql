@@ -0,0 +1,10 @@
+-- The webhook dispatcher only polls pending deliveries that are due
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at)
+    WHERE state = 'PENDING' AND deleted_at IS NULL;
+
+-- Recipient throttling compares addresses case-insensitively over a recent window
+CREATE INDEX IF NOT EXISTS idx_emails_recipient_lower_created_at ON emails (LOWER(recipient), created_at);
+
+-- Active suppressions are looked up for every email, expired and deleted entries never match
+CREATE INDEX IF NOT EXISTS idx_suppressions_active_address ON suppressions (address)
+    WHERE deleted_at IS NULL;
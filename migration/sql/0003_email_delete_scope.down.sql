@@ -0,0 +1,3 @@
+-- Keys granted email:delete after the upgrade cannot be told apart from keys that were
+-- granted it by the up migration, so they keep the scope
+SELECT 1;
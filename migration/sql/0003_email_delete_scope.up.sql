@@ -0,0 +1,5 @@
+-- Deleting and restoring emails moved from email:write to email:delete, keys that could
+-- delete before keep doing so
+UPDATE api_keys SET scopes = scopes || ',email:delete'
+    WHERE ',' || scopes || ',' LIKE '%,email:write,%'
+    AND ',' || scopes || ',' NOT LIKE '%,email:delete,%';
@@ -0,0 +1,3 @@
+-- Websites are now deleted for good, rows soft deleted before kept their code taken in
+-- idx_websites_code
+DELETE FROM websites WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,3 @@
+-- SMTP profiles are now deleted for good, rows soft deleted before kept their name taken in
+-- idx_smtp_profiles_name
+DELETE FROM smtp_profiles WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,3 @@
+-- DKIM keys are now deleted for good, rows soft deleted before kept their domain taken in
+-- idx_dkim_keys_domain
+DELETE FROM dkim_keys WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,9 @@
+-- Tokens of accepted provider webhooks, used to reject replays
+CREATE TABLE IF NOT EXISTS webhook_tokens (
+    id         bigserial PRIMARY KEY,
+    provider   text,
+    token      text,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_tokens_token ON webhook_tokens (provider, token);
+CREATE INDEX IF NOT EXISTS idx_webhook_tokens_created_at ON webhook_tokens (created_at);
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ------------------- Enums ------------------- //
+
+type APIScope string
+
+const (
+	ScopeEmailSend     APIScope = "email:send"
+	ScopeEmailRead     APIScope = "email:read"
+	ScopeEmailWrite    APIScope = "email:write"
+	ScopeEmailDelete   APIScope = "email:delete"
+	ScopeTemplateWrite APIScope = "template:write"
+)
+
+func (s APIScope) IsValid() bool {
+	switch s {
+	case ScopeEmailSend, ScopeEmailRead, ScopeEmailWrite, ScopeEmailDelete, ScopeTemplateWrite:
+		return true
+	}
+	return false
+}
+
+// ------------------- API Key Model ------------------- //
+
+// APIKey authenticates a service, only a hash of the key is stored and the key itself is
+// returned once on creation
+type APIKey struct {
+	gorm.Model
+	Name        string               `json:"name" validate:"required"`
+	Prefix      string               `json:"prefix" gorm:"uniqueIndex"`
+	Hash        string               `json:"-"`
+	Scopes      StringList[APIScope] `json:"scopes" gorm:"type:text" validate:"dive,api_scope"`
+	CompanyUUID uuid.UUID            `json:"company_uuid" gorm:"index" validate:"required_without=Admin"`
+	Admin       bool                 `json:"admin"`
+	LastUsedAt  *time.Time           `json:"last_used_at"`
+	ExpiresAt   *time.Time           `json:"expires_at"`
+}
+
+// ------------------- Custom Validations ------------------- //
+
+func ValidateAPIScope(fl validator.FieldLevel) bool {
+	scope, ok := fl.Field().Interface().(APIScope)
+	return ok && scope.IsValid()
+}
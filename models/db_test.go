@@ -0,0 +1,37 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB opens a SQLite database in the test's temporary directory with the tables of
+// emails and the rows their hooks write
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return openTestDBAt(t, filepath.Join(t.TempDir(), "models.db"))
+}
+
+func openTestDBAt(t *testing.T, path string) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+path+"?_busy_timeout=5000"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	err = db.AutoMigrate(&Email{}, &SendingQuota{}, &Suppression{}, &WebhookSubscription{}, &WebhookDelivery{}, &SMTPProfile{}, &WebhookToken{})
+	if err != nil {
+		t.Fatalf("migrating database: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"github.com/farhan-nahid/email-service/utils"
+	"gorm.io/gorm"
+)
+
+// ------------------- DKIM Key Model ------------------- //
+
+// DKIMKey signs outgoing mail for the sender domain
+type DKIMKey struct {
+	gorm.Model
+	Domain     string          `json:"domain" gorm:"uniqueIndex" validate:"required,fqdn"`
+	Selector   string          `json:"selector" validate:"required"`
+	PrivateKey EncryptedString `json:"private_key" gorm:"type:text;serializer:encrypted" validate:"required"`
+	DNSRecord  string          `json:"dns_record" gorm:"-"`
+}
+
+// AfterFind hook to expose the TXT record that has to be published for the key
+func (k *DKIMKey) AfterFind(tx *gorm.DB) (err error) {
+	k.DNSRecord, _ = utils.DKIMDNSRecord(string(k.PrivateKey))
+	return nil
+}
+
+// Key converts the model to the key used when signing
+func (k *DKIMKey) Key() *utils.DKIMKey {
+	return &utils.DKIMKey{
+		Domain:     k.Domain,
+		Selector:   k.Selector,
+		PrivateKey: string(k.PrivateKey),
+	}
+}
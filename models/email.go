@@ -3,7 +3,9 @@ package models
 import (
 	"errors"
 	"net/mail"
+	"strings"
 
+	"github.com/farhan-nahid/email-service/utils"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -41,18 +43,29 @@ func (e EmailAddress) Value() (interface{}, error) {
 type Status string
 
 const (
-	Sent   Status = "SENT"
-	Failed Status = "FAILED"
+	Sent       Status = "SENT"
+	Failed     Status = "FAILED"
+	Suppressed Status = "SUPPRESSED"
+	Bounced    Status = "BOUNCED"
+	Complained Status = "COMPLAINED"
+	Delivered  Status = "DELIVERED"
+	Throttled  Status = "THROTTLED"
 )
 
 func (s Status) IsValid() bool {
 	switch s {
-	case Sent, Failed:
+	case Sent, Failed, Suppressed, Bounced, Complained, Delivered, Throttled:
 		return true
 	}
 	return false
 }
 
+// IsProviderReported reports whether the status is only set from provider events, bounces
+// and complaints suppress the recipient for every company
+func (s Status) IsProviderReported() bool {
+	return s == Bounced || s == Complained || s == Delivered
+}
+
 
 type Source string
 
@@ -68,6 +81,18 @@ const (
 	DeleteAccount         Source = "DELETE_ACCOUNT"
 )
 
+// Category groups the sources recipients can unsubscribe from, an empty category means the
+// source is transactional or security related and is always sent
+func (s Source) Category() string {
+	switch s {
+	case TrialExpired:
+		return "trial"
+	case SubscriptionRenewed:
+		return "subscription"
+	}
+	return ""
+}
+
 func (s Source) IsValid() bool {
 	switch s {
 	case TrialCreated, TrialExpired, SubscriptionCreated, SubscriptionRenewed, 
@@ -78,36 +103,44 @@ func (s Source) IsValid() bool {
 }
 
 
+// Website is the code of a product in the website registry
 type Website string
 
+// Products seeded into the website registry
 const (
 	IK  Website = "IK"
 	MYE Website = "MYE"
 	AK  Website = "AK"
 )
 
+// IsValid only checks the code format, whether the website exists is decided by the registry
 func (w Website) IsValid() bool {
-	switch w {
-	case IK, MYE, AK:
-		return true
-	}
-	return false
+	return websiteCodePattern.MatchString(string(w))
 }
 
 // ------------------- Email Model ------------------- //
 
 type Email struct {
 	gorm.Model
-	UUID        uuid.UUID    `json:"uuid" gorm:"primaryKey;unique;"`
-	CompanyUUID uuid.UUID    `json:"company_uuid" gorm:"index" validate:"required,uuid"`
-	Name        string       `json:"name" validate:"required"`
-	Sender      EmailAddress `json:"sender" validate:"required,email_address"`
-	Recipient   EmailAddress `json:"receiver" validate:"required,email_address"`
-	Subject     string       `json:"subject" validate:"required"`
-	Status      Status       `json:"status" validate:"status"`
-	Source      Source       `json:"source" validate:"required,source"`
-	Website     Website      `json:"website" validate:"required,website"`
-	Payload     string       `json:"payload" validate:"required,json"`
+	UUID         uuid.UUID    `json:"uuid" gorm:"primaryKey;unique;"`
+	CompanyUUID  uuid.UUID    `json:"company_uuid" gorm:"index" validate:"required,uuid"`
+	Name         string       `json:"name" validate:"required"`
+	Sender       EmailAddress `json:"sender" validate:"omitempty,email_address"`
+	Recipient    EmailAddress `json:"receiver" gorm:"index" validate:"required,email_address"`
+	Subject      string       `json:"subject" validate:"required"`
+	Status       Status       `json:"status" validate:"status"`
+	StatusReason string       `json:"status_reason"`
+	Source       Source       `json:"source" validate:"required,source"`
+	Website      Website      `json:"website" gorm:"index" validate:"required,website"`
+	Payload      string       `json:"payload" validate:"required,json"`
+	MessageID    string       `json:"message_id" gorm:"index"`
+
+	// TrackOpens and TrackClicks override the website tracking settings for a single request
+	TrackOpens  *bool `json:"track_opens,omitempty" gorm:"-"`
+	TrackClicks *bool `json:"track_clicks,omitempty" gorm:"-"`
+
+	// previousStatus is the status loaded from the database, used to detect status changes
+	previousStatus Status
 }
 
 // BeforeCreate hook to set UUID automatically
@@ -118,6 +151,57 @@ func (e *Email) BeforeCreate(tx *gorm.DB) (err error) {
 	return nil
 }
 
+// AssignMessageID sets the UUID and a stable Message-ID before the email is sent,
+// bounces and delivery events are matched back to the email through it
+func (e *Email) AssignMessageID() {
+	if e.UUID == uuid.Nil {
+		e.UUID = uuid.New()
+	}
+
+	domain := "localhost"
+	if address, err := mail.ParseAddress(string(e.Sender)); err == nil {
+		domain = address.Address[strings.LastIndex(address.Address, "@")+1:]
+	}
+	e.MessageID = "<" + e.UUID.String() + "@" + domain + ">"
+}
+
+// AfterFind hook to remember the stored status
+func (e *Email) AfterFind(tx *gorm.DB) (err error) {
+	e.previousStatus = e.Status
+	return nil
+}
+
+// AfterSave hook to notify webhook subscribers of status changes and to
+// suppress recipients that hard bounced or complained
+func (e *Email) AfterSave(tx *gorm.DB) (err error) {
+	if e.Status == e.previousStatus {
+		return nil
+	}
+	e.previousStatus = e.Status
+	utils.EmailStatusChanges.WithLabelValues(string(e.Website), string(e.Source), string(e.Status)).Inc()
+
+	db := tx.Session(&gorm.Session{NewDB: true})
+	if err := EnqueueWebhooks(db, e); err != nil {
+		return err
+	}
+
+	suppression := Suppression{Address: e.Recipient, Scope: ScopeGlobal}
+
+	switch e.Status {
+	case Bounced:
+		suppression.Reason = HardBounce
+	case Complained:
+		// A complaint is about one product, keep sending the others
+		suppression.Scope = ScopeWebsite
+		suppression.Website = e.Website
+		suppression.Reason = Complaint
+	default:
+		return nil
+	}
+
+	return Suppress(db, suppression)
+}
+
 // IsValid validates the Email struct fields
 func (e *Email) IsValid() bool {
 	return e.Sender.IsValid() &&
@@ -136,6 +220,11 @@ func RegisterCustomValidations(v *validator.Validate) {
 	v.RegisterValidation("website", ValidateWebsite)
 	v.RegisterValidation("uuid", ValidateUUID)
 	v.RegisterValidation("email_address", ValidateEmailAddress)
+	v.RegisterValidation("tls_mode", ValidateTLSMode)
+	v.RegisterValidation("auth_mechanism", ValidateAuthMechanism)
+	v.RegisterValidation("suppression_scope", ValidateSuppressionScope)
+	v.RegisterValidation("suppression_reason", ValidateSuppressionReason)
+	v.RegisterValidation("api_scope", ValidateAPIScope)
 }
 
 func ValidateStatus(fl validator.FieldLevel) bool {
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ------------------- Enums ------------------- //
+
+type EventType string
+
+const (
+	EventDelivered  EventType = "DELIVERED"
+	EventBounced    EventType = "BOUNCED"
+	EventComplained EventType = "COMPLAINED"
+	EventOpened     EventType = "OPENED"
+	EventClicked    EventType = "CLICKED"
+	EventDeferred   EventType = "DEFERRED"
+)
+
+// Status returns the email status the event moves an email to, events like opens
+// and deferrals don't change the status
+func (t EventType) Status() (Status, bool) {
+	switch t {
+	case EventDelivered:
+		return Delivered, true
+	case EventBounced:
+		return Bounced, true
+	case EventComplained:
+		return Complained, true
+	}
+	return "", false
+}
+
+// ------------------- Email Event Model ------------------- //
+
+// EmailEvent is a delivery event for an email normalized from any source
+type EmailEvent struct {
+	gorm.Model
+	EmailUUID  uuid.UUID    `json:"email_uuid" gorm:"index"`
+	Type       EventType    `json:"type"`
+	Provider   string       `json:"provider"`
+	Recipient  EmailAddress `json:"recipient"`
+	Reason     string       `json:"reason"`
+	URL        string       `json:"url"`
+	UserAgent  string       `json:"user_agent"`
+	IPHash     string       `json:"ip_hash"`
+	OccurredAt time.Time    `json:"occurred_at"`
+}
+
+// TrackingStats aggregates the engagement events of an email
+type TrackingStats struct {
+	Opens         int64      `json:"opens"`
+	UniqueOpens   int64      `json:"unique_opens"`
+	FirstOpenedAt *time.Time `json:"first_opened_at"`
+	LastOpenedAt  *time.Time `json:"last_opened_at"`
+	Clicks        int64      `json:"clicks"`
+	UniqueClicks  int64      `json:"unique_clicks"`
+	Links         []LinkStat `json:"links"`
+}
+
+// LinkStat counts the clicks on one link of an email
+type LinkStat struct {
+	URL    string `json:"url"`
+	Clicks int64  `json:"clicks"`
+}
+
+// GetTrackingStats aggregates the open and click events recorded for the email
+func GetTrackingStats(db *gorm.DB, emailUUID uuid.UUID) (TrackingStats, error) {
+	stats := TrackingStats{Links: []LinkStat{}}
+	events := func(eventType EventType) *gorm.DB {
+		return db.Model(&EmailEvent{}).Where("email_uuid = ? AND type = ?", emailUUID, eventType)
+	}
+
+	if err := events(EventClicked).Count(&stats.Clicks).Error; err != nil {
+		return stats, err
+	}
+	if stats.Clicks > 0 {
+		if err := events(EventClicked).Distinct("ip_hash").Count(&stats.UniqueClicks).Error; err != nil {
+			return stats, err
+		}
+		err := events(EventClicked).
+			Select("url, COUNT(*) AS clicks").
+			Group("url").
+			Order("clicks DESC").
+			Scan(&stats.Links).Error
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	if err := events(EventOpened).Count(&stats.Opens).Error; err != nil || stats.Opens == 0 {
+		return stats, err
+	}
+	if err := events(EventOpened).Distinct("ip_hash").Count(&stats.UniqueOpens).Error; err != nil {
+		return stats, err
+	}
+
+	var first, last EmailEvent
+	if err := events(EventOpened).Order("occurred_at").First(&first).Error; err != nil {
+		return stats, err
+	}
+	if err := events(EventOpened).Order("occurred_at DESC").First(&last).Error; err != nil {
+		return stats, err
+	}
+	stats.FirstOpenedAt = &first.OccurredAt
+	stats.LastOpenedAt = &last.OccurredAt
+
+	return stats, nil
+}
+
+// ApplyEvent stores the event and moves the email to the matching status, a delivery never
+// overrides a bounce or complaint that arrived first
+func ApplyEvent(db *gorm.DB, email *Email, event EmailEvent) error {
+	event.EmailUUID = email.UUID
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	if err := db.Create(&event).Error; err != nil {
+		return err
+	}
+
+	status, ok := event.Type.Status()
+	if !ok || email.Status == status || (status == Delivered && email.Status != Sent) {
+		return nil
+	}
+
+	email.Status = status
+	email.StatusReason = event.Reason
+	return db.Save(email).Error
+}
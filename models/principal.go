@@ -0,0 +1,100 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ------------------- Roles ------------------- //
+
+// Role is granted to dashboard users by the identity provider
+type Role string
+
+const (
+	RoleViewer  Role = "viewer"
+	RoleEditor  Role = "editor"
+	RoleManager Role = "manager"
+	RoleAdmin   Role = "admin"
+)
+
+// roleScopes lists what each role may do, deleting, restoring and editing templates is
+// reserved for managers and admins
+var roleScopes = map[Role][]APIScope{
+	RoleViewer:  {ScopeEmailRead},
+	RoleEditor:  {ScopeEmailRead, ScopeEmailSend, ScopeEmailWrite},
+	RoleManager: {ScopeEmailRead, ScopeEmailSend, ScopeEmailWrite, ScopeEmailDelete, ScopeTemplateWrite},
+}
+
+func (r Role) IsValid() bool {
+	_, ok := roleScopes[r]
+	return ok || r == RoleAdmin
+}
+
+// ------------------- Principal ------------------- //
+
+// Principal is the authenticated caller of a request, either an API key or a dashboard user
+type Principal struct {
+	// ID identifies the caller across requests, used for rate limiting
+	ID        string
+	Name      string
+	Admin     bool
+	Companies []uuid.UUID
+	Roles     StringList[Role]
+	Scopes    StringList[APIScope]
+}
+
+// PrincipalFromRoles builds a dashboard user, unknown roles and companies are ignored
+func PrincipalFromRoles(subject string, roles []string, companies []string) *Principal {
+	principal := &Principal{ID: "user:" + subject, Name: subject}
+
+	for _, role := range roles {
+		if !Role(role).IsValid() {
+			continue
+		}
+		principal.Roles = append(principal.Roles, Role(role))
+		if Role(role) == RoleAdmin {
+			principal.Admin = true
+		}
+		for _, scope := range roleScopes[Role(role)] {
+			if !principal.Scopes.Contains(scope) {
+				principal.Scopes = append(principal.Scopes, scope)
+			}
+		}
+	}
+
+	for _, company := range companies {
+		if companyUUID, err := uuid.Parse(company); err == nil {
+			principal.Companies = append(principal.Companies, companyUUID)
+		}
+	}
+	return principal
+}
+
+// HasScope reports whether the caller may use endpoints requiring the scope, admins may use all
+func (p *Principal) HasScope(scope APIScope) bool {
+	return p.Admin || p.Scopes.Contains(scope)
+}
+
+// CanAccessCompany reports whether the caller may see data of the company
+func (p *Principal) CanAccessCompany(companyUUID uuid.UUID) bool {
+	if p.Admin {
+		return true
+	}
+	for _, company := range p.Companies {
+		if company == companyUUID {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeToCompany restricts a query to the caller's companies unless the caller is an admin
+func (p *Principal) ScopeToCompany(db *gorm.DB) *gorm.DB {
+	if p.Admin {
+		return db
+	}
+	if len(p.Companies) == 0 {
+		return db.Where("1 = 0")
+	}
+	return db.Where("company_uuid IN ?", p.Companies)
+}
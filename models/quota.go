@@ -0,0 +1,132 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ------------------- Sending Quota Model ------------------- //
+
+// SendingQuota overrides the QUOTA_* defaults for a single company or website, a limit of
+// zero means unlimited
+type SendingQuota struct {
+	gorm.Model
+	CompanyUUID  uuid.UUID `json:"company_uuid" gorm:"uniqueIndex:idx_sending_quota_subject" validate:"required_without=Website"`
+	Website      Website   `json:"website" gorm:"uniqueIndex:idx_sending_quota_subject" validate:"omitempty,website,excluded_with=CompanyUUID"`
+	HourlyLimit  int       `json:"hourly_limit" validate:"gte=0"`
+	DailyLimit   int       `json:"daily_limit" validate:"gte=0"`
+	MonthlyLimit int       `json:"monthly_limit" validate:"gte=0"`
+}
+
+// quotaExemptStatuses are not counted because they never reached the SMTP server
+var quotaExemptStatuses = []Status{Suppressed, Throttled}
+
+// QuotaWindow is the usage of one limit in its current calendar window
+type QuotaWindow struct {
+	Subject string
+	Period  string
+	Limit   int
+	Used    int64
+	ResetAt time.Time
+}
+
+// Remaining is the number of emails that can still be sent in the window
+func (w *QuotaWindow) Remaining() int64 {
+	if remaining := int64(w.Limit) - w.Used; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Exceeded reports whether another email would go over the limit
+func (w *QuotaWindow) Exceeded() bool {
+	return w.Used >= int64(w.Limit)
+}
+
+func (w *QuotaWindow) Error() string {
+	return fmt.Sprintf("sending quota of %d emails per %s exceeded for %s", w.Limit, w.Period, w.Subject)
+}
+
+// CheckSendingQuota returns the company or website window with the fewest emails left,
+// or nil when neither has a limit. Windows are calendar hours, days and months in UTC and
+// deleted emails still count towards them
+func CheckSendingQuota(db *gorm.DB, defaults config.QuotaConfig, companyUUID uuid.UUID, website Website, now time.Time) (*QuotaWindow, error) {
+	var tightest *QuotaWindow
+
+	subjects := []struct {
+		name     string
+		column   string
+		value    interface{}
+		defaults config.QuotaLimits
+	}{
+		{"company", "company_uuid", companyUUID, defaults.Company},
+		{"website", "website", website, defaults.Website},
+	}
+
+	for _, subject := range subjects {
+		quota, err := sendingQuotaFor(db, subject.column, subject.value, subject.defaults)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, window := range quotaWindows(quota, now) {
+			if window.limit == 0 {
+				continue
+			}
+
+			var used int64
+			err := db.Unscoped().Model(&Email{}).
+				Where(subject.column+" = ? AND created_at >= ? AND status NOT IN ?", subject.value, window.start, quotaExemptStatuses).
+				Count(&used).Error
+			if err != nil {
+				return nil, err
+			}
+
+			current := &QuotaWindow{Subject: subject.name, Period: window.period, Limit: window.limit, Used: used, ResetAt: window.end}
+			if tightest == nil || current.Remaining() < tightest.Remaining() ||
+				(current.Exceeded() && tightest.Exceeded() && current.ResetAt.After(tightest.ResetAt)) {
+				tightest = current
+			}
+		}
+	}
+	return tightest, nil
+}
+
+type quotaWindow struct {
+	period     string
+	limit      int
+	start, end time.Time
+}
+
+func quotaWindows(quota SendingQuota, now time.Time) []quotaWindow {
+	now = now.UTC()
+	hour := now.Truncate(time.Hour)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	return []quotaWindow{
+		{"hour", quota.HourlyLimit, hour, hour.Add(time.Hour)},
+		{"day", quota.DailyLimit, day, day.AddDate(0, 0, 1)},
+		{"month", quota.MonthlyLimit, month, month.AddDate(0, 1, 0)},
+	}
+}
+
+// sendingQuotaFor loads the stored override or falls back to the configured QUOTA_COMPANY_*
+// or QUOTA_WEBSITE_* limits
+func sendingQuotaFor(db *gorm.DB, column string, value interface{}, defaults config.QuotaLimits) (SendingQuota, error) {
+	var quota SendingQuota
+	err := db.Where(column+" = ?", value).First(&quota).Error
+	if err == nil {
+		return quota, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return quota, err
+	}
+
+	return SendingQuota{HourlyLimit: defaults.Hourly, DailyLimit: defaults.Daily, MonthlyLimit: defaults.Monthly}, nil
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/google/uuid"
+)
+
+func TestQuotaWindows(t *testing.T) {
+	quota := SendingQuota{HourlyLimit: 1, DailyLimit: 2, MonthlyLimit: 3}
+	at := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatalf("parsing %s: %v", value, err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		now                   string
+		hour, day, month, end string
+	}{
+		{"2026-03-15T10:42:17Z", "2026-03-15T10:00:00Z", "2026-03-15T00:00:00Z", "2026-03-01T00:00:00Z", "2026-04-01T00:00:00Z"},
+		// Windows are calendar periods in UTC whatever the zone of now
+		{"2026-03-15T01:30:00+02:00", "2026-03-14T23:00:00Z", "2026-03-14T00:00:00Z", "2026-03-01T00:00:00Z", "2026-04-01T00:00:00Z"},
+		{"2026-12-31T23:59:59Z", "2026-12-31T23:00:00Z", "2026-12-31T00:00:00Z", "2026-12-01T00:00:00Z", "2027-01-01T00:00:00Z"},
+		{"2028-02-29T12:00:00Z", "2028-02-29T12:00:00Z", "2028-02-29T00:00:00Z", "2028-02-01T00:00:00Z", "2028-03-01T00:00:00Z"},
+	}
+
+	for _, test := range tests {
+		windows := quotaWindows(quota, at(test.now))
+		if len(windows) != 3 {
+			t.Fatalf("%s: expected hour, day and month windows, got %+v", test.now, windows)
+		}
+
+		expected := []quotaWindow{
+			{"hour", 1, at(test.hour), at(test.hour).Add(time.Hour)},
+			{"day", 2, at(test.day), at(test.day).AddDate(0, 0, 1)},
+			{"month", 3, at(test.month), at(test.end)},
+		}
+		for i, window := range windows {
+			if window.period != expected[i].period || window.limit != expected[i].limit ||
+				!window.start.Equal(expected[i].start) || !window.end.Equal(expected[i].end) {
+				t.Errorf("%s: expected %+v, got %+v", test.now, expected[i], window)
+			}
+		}
+	}
+}
+
+func TestCheckSendingQuota(t *testing.T) {
+	company := uuid.New()
+	now := time.Date(2026, 3, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		defaults config.QuotaConfig
+		override *SendingQuota
+		sent     []Status
+		subject  string
+		period   string
+		used     int64
+		exceeded bool
+	}{
+		{name: "no limits"},
+		{
+			name:     "company hourly limit",
+			defaults: config.QuotaConfig{Company: config.QuotaLimits{Hourly: 3, Daily: 10}},
+			sent:     []Status{Sent, Failed},
+			subject:  "company", period: "hour", used: 2,
+		},
+		{
+			name:     "suppressed and throttled emails are not counted",
+			defaults: config.QuotaConfig{Company: config.QuotaLimits{Hourly: 2}},
+			sent:     []Status{Sent, Suppressed, Throttled},
+			subject:  "company", period: "hour", used: 1,
+		},
+		{
+			name:     "the tightest window wins",
+			defaults: config.QuotaConfig{Company: config.QuotaLimits{Hourly: 5}, Website: config.QuotaLimits{Daily: 2}},
+			sent:     []Status{Sent, Sent},
+			subject:  "website", period: "day", used: 2, exceeded: true,
+		},
+		{
+			name:     "stored overrides replace the defaults",
+			defaults: config.QuotaConfig{Company: config.QuotaLimits{Hourly: 1}},
+			override: &SendingQuota{CompanyUUID: company, MonthlyLimit: 100},
+			sent:     []Status{Sent},
+			subject:  "company", period: "month", used: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := openTestDB(t)
+			if test.override != nil {
+				if err := db.Create(test.override).Error; err != nil {
+					t.Fatalf("storing quota: %v", err)
+				}
+			}
+
+			// An email from the previous hour only counts towards the day and month
+			old := Email{CompanyUUID: company, Website: IK, Recipient: "old@example.com", Status: Sent}
+			old.CreatedAt = now.Add(-time.Hour)
+			emails := []Email{old}
+			for _, status := range test.sent {
+				email := Email{CompanyUUID: company, Website: IK, Recipient: "bob@example.com", Status: status}
+				email.CreatedAt = now.Add(-time.Minute)
+				emails = append(emails, email)
+			}
+			if err := db.Create(&emails).Error; err != nil {
+				t.Fatalf("storing emails: %v", err)
+			}
+			// used lists the emails of this hour, the older one counts towards longer windows
+			if test.period != "hour" && test.subject != "" {
+				test.used++
+			}
+
+			window, err := CheckSendingQuota(db, test.defaults, company, IK, now)
+			if err != nil {
+				t.Fatalf("checking quota: %v", err)
+			}
+			if test.subject == "" {
+				if window != nil {
+					t.Fatalf("expected no quota, got %+v", window)
+				}
+				return
+			}
+			if window == nil || window.Subject != test.subject || window.Period != test.period ||
+				window.Used != test.used || window.Exceeded() != test.exceeded {
+				t.Fatalf("expected %s/%s used %d exceeded %v, got %+v", test.subject, test.period, test.used, test.exceeded, window)
+			}
+		})
+	}
+}
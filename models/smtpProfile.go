@@ -0,0 +1,146 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ------------------- EncryptedString Type ------------------- //
+
+// EncryptedString is encrypted at rest and masked in JSON responses, columns use the
+// encrypted serializer. gorm skips serializers for single column updates, so secrets are
+// written with Save or Updates of the struct
+type EncryptedString string
+
+func init() {
+	schema.RegisterSerializer("encrypted", encryptedSerializer{})
+}
+
+// encryptedSerializer encrypts with the key UseEncryptionKey bound to the statement context
+type encryptedSerializer struct{}
+
+func (encryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var str string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	default:
+		return errors.New("failed to scan Encrypted String: value is not a string")
+	}
+
+	plaintext, err := utils.Decrypt(utils.EncryptionKey(ctx), str)
+	if err != nil {
+		return err
+	}
+	field.ReflectValueOf(ctx, dst).SetString(plaintext)
+	return nil
+}
+
+func (encryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, _ := fieldValue.(EncryptedString)
+	if value == "" {
+		return "", nil
+	}
+	return utils.Encrypt(utils.EncryptionKey(ctx), string(value))
+}
+
+// UseEncryptionKey binds the ENCRYPTION_KEY to every statement run on the database, so each
+// app instance encrypts with its own key whatever context its queries run with
+func UseEncryptionKey(db *gorm.DB, key string) error {
+	bind := func(tx *gorm.DB) {
+		tx.Statement.Context = utils.WithEncryptionKey(tx.Statement.Context, key)
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("encryption:create", bind),
+		callbacks.Query().Before("gorm:query").Register("encryption:query", bind),
+		callbacks.Update().Before("gorm:update").Register("encryption:update", bind),
+	)
+}
+
+func (e EncryptedString) MarshalJSON() ([]byte, error) {
+	if e == "" {
+		return []byte(`""`), nil
+	}
+	return []byte(`"********"`), nil
+}
+
+// ------------------- Enums ------------------- //
+
+type TLSMode string
+
+func (t TLSMode) IsValid() bool {
+	switch t {
+	case utils.TLSModeNone, utils.TLSModeStartTLS, utils.TLSModeTLS:
+		return true
+	}
+	return false
+}
+
+type AuthMechanism string
+
+func (a AuthMechanism) IsValid() bool {
+	switch a {
+	case "", utils.AuthNone, utils.AuthPlain, utils.AuthLogin, utils.AuthCRAMMD5:
+		return true
+	}
+	return false
+}
+
+// ------------------- SMTP Profile Models ------------------- //
+
+// SMTPProfile is a named set of SMTP settings selectable per website or company
+type SMTPProfile struct {
+	gorm.Model
+	Name          string          `json:"name" gorm:"uniqueIndex" validate:"required,max=64"`
+	Host          string          `json:"host" validate:"required,hostname|ip"`
+	Port          int             `json:"port" validate:"required,min=1,max=65535"`
+	TLSMode       TLSMode         `json:"tls_mode" validate:"required,tls_mode"`
+	AuthMechanism AuthMechanism   `json:"auth_mechanism" validate:"auth_mechanism"`
+	Username      string          `json:"username"`
+	Password      EncryptedString `json:"password" gorm:"type:text;serializer:encrypted"`
+}
+
+// Config converts the profile to the settings used to dial the server
+func (p *SMTPProfile) Config() utils.SMTPConfig {
+	return utils.SMTPConfig{
+		Host:          p.Host,
+		Port:          p.Port,
+		Username:      p.Username,
+		Password:      string(p.Password),
+		TLSMode:       string(p.TLSMode),
+		AuthMechanism: string(p.AuthMechanism),
+	}
+}
+
+// CompanySMTPProfile overrides the website profile for a company,
+// an empty website applies the override to every website
+type CompanySMTPProfile struct {
+	gorm.Model
+	CompanyUUID uuid.UUID `json:"company_uuid" gorm:"index" validate:"required,uuid"`
+	Website     Website   `json:"website" validate:"omitempty,website"`
+	ProfileName string    `json:"profile_name" validate:"required"`
+}
+
+// ------------------- Custom Validations ------------------- //
+
+func ValidateTLSMode(fl validator.FieldLevel) bool {
+	mode, ok := fl.Field().Interface().(TLSMode)
+	return ok && mode.IsValid()
+}
+
+func ValidateAuthMechanism(fl validator.FieldLevel) bool {
+	mechanism, ok := fl.Field().Interface().(AuthMechanism)
+	return ok && mechanism.IsValid()
+}
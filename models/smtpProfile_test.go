@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptedSerializer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.db")
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	otherKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("o", 32)))
+
+	db := openTestDBAt(t, path)
+	if err := UseEncryptionKey(db, key); err != nil {
+		t.Fatalf("binding key: %v", err)
+	}
+
+	profile := SMTPProfile{Name: "relay", Host: "smtp.example.com", Port: 587, TLSMode: "STARTTLS", Password: "relay-password"}
+	if err := db.Create(&profile).Error; err != nil {
+		t.Fatalf("storing profile: %v", err)
+	}
+	if err := db.Create(&SMTPProfile{Name: "open-relay", Host: "smtp.example.com", Port: 25, TLSMode: "NONE"}).Error; err != nil {
+		t.Fatalf("storing profile: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		stored   func(string) bool
+		password EncryptedString
+	}{
+		{"relay", func(raw string) bool {
+			return strings.HasPrefix(raw, "enc:v1:") && !strings.Contains(raw, "relay-password")
+		}, "relay-password"},
+		{"open-relay", func(raw string) bool { return raw == "" }, ""},
+	}
+	for _, test := range tests {
+		var raw string
+		db.Raw("SELECT password FROM smtp_profiles WHERE name = ?", test.name).Scan(&raw)
+		if !test.stored(raw) {
+			t.Errorf("%s: unexpected stored password %q", test.name, raw)
+		}
+
+		var loaded SMTPProfile
+		if err := db.Where("name = ?", test.name).First(&loaded).Error; err != nil || loaded.Password != test.password {
+			t.Errorf("%s: expected password %q, got %q: %v", test.name, test.password, loaded.Password, err)
+		}
+	}
+
+	// Struct updates are encrypted too, single column updates skip serializers
+	if err := db.Model(&profile).Updates(SMTPProfile{Password: "rotated-password"}).Error; err != nil {
+		t.Fatalf("updating password: %v", err)
+	}
+	var loaded SMTPProfile
+	if err := db.First(&loaded, profile.ID).Error; err != nil || loaded.Password != "rotated-password" {
+		t.Fatalf("expected the rotated password, got %q: %v", loaded.Password, err)
+	}
+
+	// Another instance with its own key can't read the secrets
+	other := openTestDBAt(t, path)
+	if err := UseEncryptionKey(other, otherKey); err != nil {
+		t.Fatalf("binding key: %v", err)
+	}
+	if err := other.First(&SMTPProfile{}, profile.ID).Error; err == nil {
+		t.Fatal("expected loading with another key to fail")
+	}
+
+	// Secrets are never written to JSON responses
+	encoded, _ := json.Marshal(loaded)
+	if strings.Contains(string(encoded), "rotated-password") || !strings.Contains(string(encoded), `"password":"********"`) {
+		t.Fatalf("the password is not masked: %s", encoded)
+	}
+}
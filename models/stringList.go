@@ -0,0 +1,52 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+)
+
+// ------------------- StringList Type ------------------- //
+
+// StringList is a list of string enums stored as a comma separated column
+type StringList[T ~string] []T
+
+func (l StringList[T]) Contains(item T) bool {
+	for _, value := range l {
+		if value == item {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *StringList[T]) Scan(value interface{}) error {
+	var str string
+	switch v := value.(type) {
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	case nil:
+		*l = nil
+		return nil
+	default:
+		return errors.New("failed to scan String List: value is not a string")
+	}
+
+	*l = nil
+	for _, item := range strings.Split(str, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, T(item))
+		}
+	}
+	return nil
+}
+
+func (l StringList[T]) Value() (driver.Value, error) {
+	items := make([]string, len(l))
+	for i, item := range l {
+		items[i] = string(item)
+	}
+	return strings.Join(items, ","), nil
+}
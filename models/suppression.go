@@ -0,0 +1,100 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ------------------- Enums ------------------- //
+
+type SuppressionScope string
+
+const (
+	ScopeGlobal  SuppressionScope = "GLOBAL"
+	ScopeWebsite SuppressionScope = "WEBSITE"
+	ScopeCompany SuppressionScope = "COMPANY"
+)
+
+func (s SuppressionScope) IsValid() bool {
+	switch s {
+	case ScopeGlobal, ScopeWebsite, ScopeCompany:
+		return true
+	}
+	return false
+}
+
+type SuppressionReason string
+
+const (
+	HardBounce  SuppressionReason = "HARD_BOUNCE"
+	Complaint   SuppressionReason = "COMPLAINT"
+	Unsubscribe SuppressionReason = "UNSUBSCRIBE"
+	Manual      SuppressionReason = "MANUAL"
+)
+
+func (r SuppressionReason) IsValid() bool {
+	switch r {
+	case HardBounce, Complaint, Unsubscribe, Manual:
+		return true
+	}
+	return false
+}
+
+// ------------------- Suppression Model ------------------- //
+
+// Suppression stops emails to an address, either everywhere, for one website or for one company
+type Suppression struct {
+	gorm.Model
+	Address     EmailAddress      `json:"address" gorm:"index" validate:"required,email_address"`
+	Scope       SuppressionScope  `json:"scope" validate:"required,suppression_scope"`
+	Website     Website           `json:"website" validate:"required_if=Scope WEBSITE,omitempty,website"`
+	CompanyUUID uuid.UUID         `json:"company_uuid" validate:"required_if=Scope COMPANY"`
+	Reason      SuppressionReason `json:"reason" validate:"required,suppression_reason"`
+	ExpiresAt   *time.Time        `json:"expires_at"`
+}
+
+// BeforeSave hook to store addresses in lower case so lookups are case insensitive
+func (s *Suppression) BeforeSave(tx *gorm.DB) (err error) {
+	s.Address = EmailAddress(strings.ToLower(string(s.Address)))
+	return nil
+}
+
+// ActiveSuppression returns the suppression blocking the recipient for the website and company,
+// gorm.ErrRecordNotFound means the recipient can be emailed
+func ActiveSuppression(db *gorm.DB, recipient EmailAddress, website Website, companyUUID uuid.UUID) (*Suppression, error) {
+	var suppression Suppression
+	err := db.
+		Where("address = ?", strings.ToLower(string(recipient))).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Where("scope = ? OR (scope = ? AND website = ?) OR (scope = ? AND company_uuid = ?)",
+			ScopeGlobal, ScopeWebsite, website, ScopeCompany, companyUUID).
+		First(&suppression).Error
+	if err != nil {
+		return nil, err
+	}
+	return &suppression, nil
+}
+
+// Suppress records a suppression unless an identical one already exists
+func Suppress(db *gorm.DB, suppression Suppression) error {
+	suppression.Address = EmailAddress(strings.ToLower(string(suppression.Address)))
+	return db.
+		Where(Suppression{Address: suppression.Address, Scope: suppression.Scope, Website: suppression.Website, CompanyUUID: suppression.CompanyUUID, Reason: suppression.Reason}).
+		FirstOrCreate(&suppression).Error
+}
+
+// ------------------- Custom Validations ------------------- //
+
+func ValidateSuppressionScope(fl validator.FieldLevel) bool {
+	scope, ok := fl.Field().Interface().(SuppressionScope)
+	return ok && scope.IsValid()
+}
+
+func ValidateSuppressionReason(fl validator.FieldLevel) bool {
+	reason, ok := fl.Field().Interface().(SuppressionReason)
+	return ok && reason.IsValid()
+}
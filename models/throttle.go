@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/farhan-nahid/email-service/config"
+	"gorm.io/gorm"
+)
+
+// throttleExemptStatuses are not counted, so a failed or rejected email can be retried
+var throttleExemptStatuses = []Status{Failed, Suppressed, Throttled}
+
+// duplicateWindow returns how long a source may only be sent once to a recipient, set per
+// source by THROTTLE_DUPLICATE_WINDOWS with THROTTLE_DUPLICATE_WINDOW as the fallback
+func duplicateWindow(cfg config.ThrottleConfig, source Source) time.Duration {
+	if window, ok := cfg.DuplicateWindows[string(source)]; ok {
+		return window
+	}
+	return cfg.DuplicateWindow
+}
+
+// ValidateThrottleConfig rejects THROTTLE_DUPLICATE_WINDOWS entries for unknown sources,
+// which would never apply
+func ValidateThrottleConfig(cfg config.ThrottleConfig) error {
+	for name := range cfg.DuplicateWindows {
+		if !Source(name).IsValid() {
+			return fmt.Errorf("invalid configuration: THROTTLE_DUPLICATE_WINDOWS names unknown source %s", name)
+		}
+	}
+	return nil
+}
+
+// CheckRecipientThrottle returns why the email must not be sent, or an empty reason. It blocks
+// duplicates of the same source and website to the recipient within the duplicate window and
+// caps emails per recipient over the last 24 hours at THROTTLE_RECIPIENT_DAILY_LIMIT
+func CheckRecipientThrottle(db *gorm.DB, cfg config.ThrottleConfig, email *Email, now time.Time) (string, error) {
+	recipient := strings.ToLower(string(email.Recipient))
+	sent := db.Unscoped().Model(&Email{}).Where("LOWER(recipient) = ? AND status NOT IN ?", recipient, throttleExemptStatuses)
+
+	if window := duplicateWindow(cfg, email.Source); window > 0 {
+		var duplicates int64
+		err := sent.Session(&gorm.Session{}).
+			Where("source = ? AND website = ? AND created_at >= ?", email.Source, email.Website, now.Add(-window)).
+			Count(&duplicates).Error
+		if err != nil {
+			return "", err
+		}
+		if duplicates > 0 {
+			return fmt.Sprintf("duplicate %s email within %s", email.Source, window), nil
+		}
+	}
+
+	if limit := cfg.RecipientDailyLimit; limit > 0 {
+		var count int64
+		err := sent.Session(&gorm.Session{}).Where("created_at >= ?", now.Add(-24*time.Hour)).Count(&count).Error
+		if err != nil {
+			return "", err
+		}
+		if count >= int64(limit) {
+			return fmt.Sprintf("recipient reached the limit of %d emails per day", limit), nil
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/google/uuid"
+)
+
+func TestCheckRecipientThrottle(t *testing.T) {
+	now := time.Date(2026, 3, 15, 10, 30, 0, 0, time.UTC)
+	cfg := config.ThrottleConfig{
+		DuplicateWindow:     10 * time.Minute,
+		DuplicateWindows:    config.DurationMap{string(ResetPassword): time.Minute},
+		RecipientDailyLimit: 3,
+	}
+
+	type sent struct {
+		source  Source
+		website Website
+		status  Status
+		ago     time.Duration
+	}
+	tests := []struct {
+		name   string
+		cfg    config.ThrottleConfig
+		sent   []sent
+		source Source
+		reason string
+	}{
+		{name: "first email", cfg: cfg, source: TrialCreated},
+		{
+			name: "duplicate within the default window", cfg: cfg, source: TrialCreated,
+			sent:   []sent{{TrialCreated, IK, Sent, 5 * time.Minute}},
+			reason: "duplicate TRIAL_CREATED email within 10m0s",
+		},
+		{
+			name: "duplicate after the default window", cfg: cfg, source: TrialCreated,
+			sent: []sent{{TrialCreated, IK, Sent, 11 * time.Minute}},
+		},
+		{
+			name: "per source window", cfg: cfg, source: ResetPassword,
+			sent: []sent{{ResetPassword, IK, Sent, 2 * time.Minute}},
+		},
+		{
+			name: "duplicate within the per source window", cfg: cfg, source: ResetPassword,
+			sent:   []sent{{ResetPassword, IK, Sent, 30 * time.Second}},
+			reason: "duplicate RESET_PASSWORD email within 1m0s",
+		},
+		{
+			name: "other website", cfg: cfg, source: TrialCreated,
+			sent: []sent{{TrialCreated, MYE, Sent, time.Minute}},
+		},
+		{
+			name: "failed and rejected emails can be retried", cfg: cfg, source: TrialCreated,
+			sent: []sent{{TrialCreated, IK, Failed, time.Minute}, {TrialCreated, IK, Suppressed, time.Minute}, {TrialCreated, IK, Throttled, time.Minute}},
+		},
+		{
+			name: "daily limit", cfg: cfg, source: TrialCreated,
+			sent:   []sent{{AccountCreation, IK, Sent, time.Hour}, {ChangeEmail, IK, Delivered, 2 * time.Hour}, {TrialExpired, AK, Bounced, 20 * time.Hour}},
+			reason: "recipient reached the limit of 3 emails per day",
+		},
+		{
+			name: "daily limit only counts the last 24 hours", cfg: cfg, source: TrialCreated,
+			sent: []sent{{AccountCreation, IK, Sent, time.Hour}, {ChangeEmail, IK, Sent, 2 * time.Hour}, {TrialExpired, AK, Sent, 25 * time.Hour}},
+		},
+		{
+			name: "throttling disabled", cfg: config.ThrottleConfig{}, source: TrialCreated,
+			sent: []sent{{TrialCreated, IK, Sent, 0}, {TrialCreated, IK, Sent, 0}, {TrialCreated, IK, Sent, 0}, {TrialCreated, IK, Sent, 0}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := openTestDB(t)
+			for _, previous := range test.sent {
+				// Addresses are compared case-insensitively
+				email := Email{CompanyUUID: uuid.New(), Recipient: "Bob@Example.com", Source: previous.source, Website: previous.website, Status: previous.status}
+				email.CreatedAt = now.Add(-previous.ago)
+				if err := db.Create(&email).Error; err != nil {
+					t.Fatalf("storing email: %v", err)
+				}
+			}
+
+			email := &Email{Recipient: "bob@example.com", Source: test.source, Website: IK}
+			reason, err := CheckRecipientThrottle(db, test.cfg, email, now)
+			if err != nil {
+				t.Fatalf("checking throttle: %v", err)
+			}
+			if reason != test.reason {
+				t.Fatalf("expected reason %q, got %q", test.reason, reason)
+			}
+		})
+	}
+}
+
+func TestValidateThrottleConfig(t *testing.T) {
+	valid := config.ThrottleConfig{DuplicateWindows: config.DurationMap{"RESET_PASSWORD": time.Minute, "CHANGE_EMAIL": time.Minute}}
+	if err := ValidateThrottleConfig(valid); err != nil {
+		t.Errorf("expected known sources to be accepted, got %v", err)
+	}
+
+	invalid := config.ThrottleConfig{DuplicateWindows: config.DurationMap{"reset_password": time.Minute}}
+	if err := ValidateThrottleConfig(invalid); err == nil || !strings.Contains(err.Error(), "reset_password") {
+		t.Errorf("expected the unknown source to be named, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ------------------- Unsubscribe Preference Model ------------------- //
+
+// UnsubscribePreference records a recipient opting out of a category of emails from a website,
+// an empty category opts out of every category
+type UnsubscribePreference struct {
+	gorm.Model
+	Recipient      EmailAddress `json:"recipient" gorm:"index"`
+	Website        Website      `json:"website"`
+	Category       string       `json:"category"`
+	UnsubscribedAt *time.Time   `json:"unsubscribed_at"`
+}
+
+// UnsubscribeToken is the signed payload of unsubscribe links
+type UnsubscribeToken struct {
+	Recipient EmailAddress `json:"r"`
+	Website   Website      `json:"w"`
+	Category  string       `json:"c"`
+}
+
+// IsUnsubscribed reports whether the recipient opted out of the category for the website
+func IsUnsubscribed(db *gorm.DB, recipient EmailAddress, website Website, category string) (bool, error) {
+	var count int64
+	err := db.Model(&UnsubscribePreference{}).
+		Where("recipient = ? AND website = ?", strings.ToLower(string(recipient)), website).
+		Where("category = ? OR category = ''", category).
+		Where("unsubscribed_at IS NOT NULL").
+		Count(&count).Error
+	return count > 0, err
+}
+
+// RecordUnsubscribe opts the recipient out of the category for the website
+func RecordUnsubscribe(db *gorm.DB, token UnsubscribeToken) error {
+	now := time.Now()
+	preference := UnsubscribePreference{
+		Recipient: EmailAddress(strings.ToLower(string(token.Recipient))),
+		Website:   token.Website,
+		Category:  token.Category,
+	}
+
+	return db.
+		Where("recipient = ? AND website = ? AND category = ?", preference.Recipient, preference.Website, preference.Category).
+		Assign(UnsubscribePreference{UnsubscribedAt: &now}).
+		FirstOrCreate(&preference).Error
+}
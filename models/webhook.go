@@ -0,0 +1,120 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ------------------- Enums ------------------- //
+
+type DeliveryState string
+
+const (
+	DeliveryPending   DeliveryState = "PENDING"
+	DeliveryDelivered DeliveryState = "DELIVERED"
+	DeliveryFailed    DeliveryState = "FAILED"
+)
+
+// ------------------- Webhook Models ------------------- //
+
+// WebhookSubscription notifies an app about email status changes, a nil company or empty
+// website matches every company or website and empty event types match every status
+type WebhookSubscription struct {
+	gorm.Model
+	CompanyUUID uuid.UUID          `json:"company_uuid" gorm:"index"`
+	Website     Website            `json:"website" validate:"omitempty,website"`
+	URL         string             `json:"url" validate:"required,url"`
+	Secret      EncryptedString    `json:"secret" gorm:"type:text;serializer:encrypted" validate:"required,min=16"`
+	EventTypes  StringList[Status] `json:"event_types" gorm:"type:text" validate:"dive,status"`
+}
+
+// WebhookDelivery is one event queued for a subscription, it doubles as the delivery log
+type WebhookDelivery struct {
+	gorm.Model
+	EventID        uuid.UUID     `json:"event_id" gorm:"uniqueIndex"`
+	SubscriptionID uint          `json:"subscription_id" gorm:"index"`
+	EmailUUID      uuid.UUID     `json:"email_uuid" gorm:"index"`
+	EventType      string        `json:"event_type"`
+	Payload        string        `json:"payload" gorm:"type:text"`
+	State          DeliveryState `json:"state" gorm:"index"`
+	Attempts       int           `json:"attempts"`
+	NextAttemptAt  time.Time     `json:"next_attempt_at" gorm:"index"`
+	LastStatusCode int           `json:"last_status_code"`
+	LastError      string        `json:"last_error"`
+	DeliveredAt    *time.Time    `json:"delivered_at"`
+
+	// TraceParent links the delivery to the trace of the status change that queued it
+	TraceParent string `json:"-"`
+}
+
+// webhookEvent is the JSON body delivered to subscribers
+type webhookEvent struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      struct {
+		UUID         uuid.UUID    `json:"uuid"`
+		CompanyUUID  uuid.UUID    `json:"company_uuid"`
+		Website      Website      `json:"website"`
+		Source       Source       `json:"source"`
+		Recipient    EmailAddress `json:"receiver"`
+		Status       Status       `json:"status"`
+		StatusReason string       `json:"status_reason"`
+		MessageID    string       `json:"message_id"`
+	} `json:"data"`
+}
+
+// EnqueueWebhooks queues a delivery of the email status to every matching subscription
+func EnqueueWebhooks(db *gorm.DB, email *Email) error {
+	var subscriptions []WebhookSubscription
+	err := db.
+		Where("company_uuid = ? OR company_uuid = ?", email.CompanyUUID, uuid.Nil).
+		Where("website = ? OR website = ''", email.Website).
+		Find(&subscriptions).Error
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, subscription := range subscriptions {
+		if len(subscription.EventTypes) > 0 && !subscription.EventTypes.Contains(email.Status) {
+			continue
+		}
+
+		event := webhookEvent{ID: uuid.New(), Type: "email." + strings.ToLower(string(email.Status)), CreatedAt: now}
+		event.Data.UUID = email.UUID
+		event.Data.CompanyUUID = email.CompanyUUID
+		event.Data.Website = email.Website
+		event.Data.Source = email.Source
+		event.Data.Recipient = email.Recipient
+		event.Data.Status = email.Status
+		event.Data.StatusReason = email.StatusReason
+		event.Data.MessageID = email.MessageID
+
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		delivery := WebhookDelivery{
+			EventID:        event.ID,
+			SubscriptionID: subscription.ID,
+			EmailUUID:      email.UUID,
+			EventType:      event.Type,
+			Payload:        string(payload),
+			State:          DeliveryPending,
+			NextAttemptAt:  now,
+			TraceParent:    utils.InjectTraceContext(db.Statement.Context),
+		}
+		if err := db.Create(&delivery).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
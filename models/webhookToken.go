@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ------------------- Webhook Token Model ------------------- //
+
+// webhookTokenRetention outlives the window in which a signed provider webhook is accepted,
+// so a token cannot be replayed once it has been forgotten
+const webhookTokenRetention = 30 * time.Minute
+
+// WebhookToken remembers the tokens of provider webhooks that were already accepted
+type WebhookToken struct {
+	ID        uint      `gorm:"primarykey"`
+	Provider  string    `gorm:"uniqueIndex:idx_webhook_tokens_token"`
+	Token     string    `gorm:"uniqueIndex:idx_webhook_tokens_token"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// ClaimWebhookToken records the token of a provider webhook and reports false when it was
+// already claimed, which means the webhook is a replay. Expired tokens are purged first
+func ClaimWebhookToken(db *gorm.DB, provider, token string, now time.Time) (bool, error) {
+	if err := db.Where("created_at < ?", now.Add(-webhookTokenRetention)).Delete(&WebhookToken{}).Error; err != nil {
+		return false, err
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&WebhookToken{Provider: provider, Token: token, CreatedAt: now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaimWebhookToken(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now()
+
+	claim := func(provider, token string, at time.Time) bool {
+		t.Helper()
+		claimed, err := ClaimWebhookToken(db, provider, token, at)
+		if err != nil {
+			t.Fatalf("claiming token: %v", err)
+		}
+		return claimed
+	}
+
+	if !claim("mailgun", "token", now) {
+		t.Fatal("expected a new token to be claimed")
+	}
+	if claim("mailgun", "token", now.Add(time.Minute)) {
+		t.Error("expected a replayed token to be rejected")
+	}
+	if !claim("other", "token", now) {
+		t.Error("expected tokens to be scoped to their provider")
+	}
+
+	// Tokens are forgotten once the webhook they came with could no longer be accepted
+	if !claim("mailgun", "token", now.Add(webhookTokenRetention+time.Minute)) {
+		t.Error("expected an expired token to be purged")
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"regexp"
+
+	"gorm.io/gorm"
+)
+
+// Sources is a list of email sources stored as a comma separated column
+type Sources = StringList[Source]
+
+// ------------------- Website Registry ------------------- //
+
+// websiteCodePattern restricts website codes to short upper case identifiers,
+// they are also used as template directory names
+var websiteCodePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,31}$`)
+
+// WebsiteConfig holds the per product settings used when sending emails,
+// adding a new product only requires a new row and a template directory
+type WebsiteConfig struct {
+	gorm.Model
+	Code           Website      `json:"code" gorm:"uniqueIndex" validate:"required,website"`
+	DisplayName    string       `json:"display_name" validate:"required"`
+	DefaultSender  EmailAddress `json:"default_sender" validate:"required,email_address"`
+	ReplyTo        EmailAddress `json:"reply_to" validate:"omitempty,email_address"`
+	LogoURL        string       `json:"logo_url" validate:"omitempty,url"`
+	PrimaryColor   string       `json:"primary_color" validate:"omitempty,hexcolor"`
+	FooterText     string       `json:"footer_text"`
+	AllowedSources Sources      `json:"allowed_sources" gorm:"type:text" validate:"dive,source"`
+	SMTPProfile    string       `json:"smtp_profile"`
+	TrackOpens     bool         `json:"track_opens"`
+	TrackClicks    bool         `json:"track_clicks"`
+}
+
+func (WebsiteConfig) TableName() string {
+	return "websites"
+}
+
+// AllowsSource reports whether the website may send emails for the given source,
+// an empty list allows every source
+func (w *WebsiteConfig) AllowsSource(source Source) bool {
+	return len(w.AllowedSources) == 0 || w.AllowedSources.Contains(source)
+}
+
+// DefaultWebsites returns the products that existed before the registry, used to seed it
+func DefaultWebsites(sender EmailAddress) []WebsiteConfig {
+	return []WebsiteConfig{
+		{Code: IK, DisplayName: "Inventory Keeper", DefaultSender: sender},
+		{Code: MYE, DisplayName: "Manage Your Ecommerce", DefaultSender: sender},
+		{Code: AK, DisplayName: "Attendance Keeper", DefaultSender: sender},
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/farhan-nahid/email-service/models"
+	"gorm.io/gorm"
+)
+
+// ------------------- Email Repository ------------------- //
+
+// EmailRepository stores emails. Queries taking a principal only see the companies it can
+// access, lookups of missing emails return gorm.ErrRecordNotFound
+type EmailRepository interface {
+	Create(ctx context.Context, email *models.Email) error
+	List(ctx context.Context, principal *models.Principal) ([]models.Email, error)
+	ListDeleted(ctx context.Context, principal *models.Principal) ([]models.Email, error)
+	// FindByUUID includes soft deleted emails when withDeleted is set
+	FindByUUID(ctx context.Context, principal *models.Principal, uuid string, withDeleted bool) (models.Email, error)
+	Save(ctx context.Context, email *models.Email) error
+	Delete(ctx context.Context, email *models.Email) error
+	Restore(ctx context.Context, email *models.Email) error
+}
+
+// gormEmailRepository works with every database GORM opens for the service, Postgres in
+// production and SQLite in tests and single binary mode
+type gormEmailRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailRepository creates the GORM backed repository
+func NewEmailRepository(db *gorm.DB) EmailRepository {
+	return &gormEmailRepository{db: db}
+}
+
+func (r *gormEmailRepository) Create(ctx context.Context, email *models.Email) error {
+	return r.db.WithContext(ctx).Create(email).Error
+}
+
+func (r *gormEmailRepository) List(ctx context.Context, principal *models.Principal) ([]models.Email, error) {
+	var emails []models.Email
+	err := principal.ScopeToCompany(r.db.WithContext(ctx)).Find(&emails).Error
+	return emails, err
+}
+
+func (r *gormEmailRepository) ListDeleted(ctx context.Context, principal *models.Principal) ([]models.Email, error) {
+	var emails []models.Email
+	err := principal.ScopeToCompany(r.db.WithContext(ctx).Unscoped()).Where("deleted_at IS NOT NULL").Find(&emails).Error
+	return emails, err
+}
+
+func (r *gormEmailRepository) FindByUUID(ctx context.Context, principal *models.Principal, uuid string, withDeleted bool) (models.Email, error) {
+	db := r.db.WithContext(ctx)
+	if withDeleted {
+		db = db.Unscoped()
+	}
+
+	var email models.Email
+	err := principal.ScopeToCompany(db).Where("uuid = ?", uuid).First(&email).Error
+	return email, err
+}
+
+func (r *gormEmailRepository) Save(ctx context.Context, email *models.Email) error {
+	return r.db.WithContext(ctx).Save(email).Error
+}
+
+func (r *gormEmailRepository) Delete(ctx context.Context, email *models.Email) error {
+	return r.db.WithContext(ctx).Delete(email).Error
+}
+
+func (r *gormEmailRepository) Restore(ctx context.Context, email *models.Email) error {
+	if err := r.db.WithContext(ctx).Unscoped().Model(email).UpdateColumn("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	email.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/farhan-nahid/email-service/controllers"
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/gin-gonic/gin"
+)
+
+func APIKeyRoute(router *gin.Engine) {
+	v1 := router.Group("/api/v1", middleware.Authenticate(), middleware.RateLimit(), middleware.RequireAdmin())
+	{
+		v1.POST("/api-key", middleware.BindAndValidate[models.APIKey](), controllers.CreateAPIKey)
+		v1.GET("/api-key", controllers.GetAPIKeys)
+		v1.DELETE("/api-key/:id", controllers.DeleteAPIKeyByID)
+	}
+}
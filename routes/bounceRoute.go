@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/farhan-nahid/email-service/controllers"
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func BounceRoute(router *gin.Engine) {
+	v1 := router.Group("/api/v1", middleware.Authenticate(), middleware.RateLimit(), middleware.RequireAdmin())
+	{
+		v1.POST("/bounce/dsn", controllers.IngestDSN)
+	}
+}
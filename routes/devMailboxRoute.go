@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/farhan-nahid/email-service/controllers"
+	"github.com/farhan-nahid/email-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// DevMailboxRoute is only registered with EMAIL_TRANSPORT=mailbox, the captured emails are
+// readable without credentials like any local mail catcher
+func DevMailboxRoute(router *gin.Engine, mailbox *utils.Mailbox) {
+	handler := controllers.NewDevMailboxHandler(mailbox)
+
+	dev := router.Group("/dev/mailbox")
+	{
+		dev.GET("", handler.ShowMailbox)
+		dev.GET("/messages", handler.GetMailboxMessages)
+		dev.DELETE("/messages", handler.ClearMailbox)
+		dev.GET("/messages/:id", handler.GetMailboxMessage)
+		dev.GET("/messages/:id/html", handler.GetMailboxMessageHTML)
+		dev.GET("/messages/:id/raw", handler.GetMailboxMessageRaw)
+		dev.GET("/messages/:id/attachments/:index", handler.GetMailboxAttachment)
+	}
+}
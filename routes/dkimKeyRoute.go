@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/farhan-nahid/email-service/controllers"
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/gin-gonic/gin"
+)
+
+func DKIMKeyRoute(router *gin.Engine) {
+	v1 := router.Group("/api/v1", middleware.Authenticate(), middleware.RateLimit(), middleware.RequireAdmin())
+	{
+		v1.POST("/dkim-key", middleware.BindAndValidate[models.DKIMKey](), controllers.CreateDKIMKey)
+		v1.GET("/dkim-key", controllers.GetDKIMKeys)
+		v1.DELETE("/dkim-key/:domain", controllers.DeleteDKIMKeyByDomain)
+	}
+}
@@ -4,17 +4,21 @@ import (
 	"github.com/farhan-nahid/email-service/controllers"
 	"github.com/farhan-nahid/email-service/middleware"
 	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/repository"
 	"github.com/gin-gonic/gin"
 )
 
-func EmailRoute(router *gin.Engine) {
-	v1 := router.Group("/api/v1")
+func EmailRoute(router *gin.Engine, emails repository.EmailRepository) {
+	handler := controllers.NewEmailHandler(emails)
+
+	v1 := router.Group("/api/v1", middleware.Authenticate(), middleware.RateLimit())
 	{
-		v1.POST("/email", middleware.BindAndValidate[models.Email](), controllers.CreateEmail)
-		v1.GET("/email", controllers.GetEmails)
-		v1.GET("/email/deleted", controllers.GetDeletedEmails)
-		v1.GET("/email/:uuid", controllers.GetEmailByUUID)
-		v1.PATCH("/email/:uuid", controllers.UpdateEmailByUUID)
-		v1.DELETE("/email/:uuid", controllers.DeleteEmailByUUID)
+		v1.POST("/email", middleware.RequireScope(models.ScopeEmailSend), middleware.BindAndValidate[models.Email](), handler.CreateEmail)
+		v1.GET("/email", middleware.RequireScope(models.ScopeEmailRead), handler.GetEmails)
+		v1.GET("/email/deleted", middleware.RequireScope(models.ScopeEmailRead), handler.GetDeletedEmails)
+		v1.GET("/email/:uuid", middleware.RequireScope(models.ScopeEmailRead), handler.GetEmailByUUID)
+		v1.PATCH("/email/:uuid", middleware.RequireScope(models.ScopeEmailWrite), handler.UpdateEmailByUUID)
+		v1.DELETE("/email/:uuid", middleware.RequireScope(models.ScopeEmailDelete), handler.DeleteEmailByUUID)
+		v1.POST("/email/:uuid/restore", middleware.RequireScope(models.ScopeEmailDelete), handler.RestoreEmailByUUID)
 	}
 }
\ No newline at end of file
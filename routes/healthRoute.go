@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/farhan-nahid/email-service/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func HealthRoute(router *gin.Engine) {
+	router.GET("/livez", controllers.Livez)
+	router.GET("/readyz", controllers.Readyz)
+}
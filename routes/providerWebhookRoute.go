@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/farhan-nahid/email-service/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func ProviderWebhookRoute(router *gin.Engine) {
+	v1 := router.Group("/api/v1")
+	{
+		v1.POST("/webhooks/:provider", controllers.IngestProviderWebhook)
+	}
+}
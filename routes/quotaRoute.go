@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/farhan-nahid/email-service/controllers"
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/gin-gonic/gin"
+)
+
+func QuotaRoute(router *gin.Engine) {
+	v1 := router.Group("/api/v1", middleware.Authenticate(), middleware.RateLimit(), middleware.RequireAdmin())
+	{
+		v1.POST("/sending-quota", middleware.BindAndValidate[models.SendingQuota](), controllers.CreateSendingQuota)
+		v1.GET("/sending-quota", controllers.GetSendingQuotas)
+		v1.DELETE("/sending-quota/:id", controllers.DeleteSendingQuotaByID)
+	}
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/farhan-nahid/email-service/controllers"
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/gin-gonic/gin"
+)
+
+func SMTPProfileRoute(router *gin.Engine) {
+	v1 := router.Group("/api/v1", middleware.Authenticate(), middleware.RateLimit(), middleware.RequireAdmin())
+	{
+		v1.POST("/smtp-profile", middleware.BindAndValidate[models.SMTPProfile](), controllers.CreateSMTPProfile)
+		v1.GET("/smtp-profile", controllers.GetSMTPProfiles)
+		v1.GET("/smtp-profile/status", controllers.GetSMTPProfileStatus)
+		v1.GET("/smtp-profile/:name", controllers.GetSMTPProfileByName)
+		v1.PATCH("/smtp-profile/:name", controllers.UpdateSMTPProfileByName)
+		v1.DELETE("/smtp-profile/:name", controllers.DeleteSMTPProfileByName)
+		v1.POST("/smtp-profile/:name/test", controllers.TestSMTPProfile)
+		v1.GET("/smtp-pool/stats", controllers.GetSMTPPoolStats)
+
+		v1.POST("/company-smtp-profile", middleware.BindAndValidate[models.CompanySMTPProfile](), controllers.CreateCompanySMTPProfile)
+		v1.GET("/company-smtp-profile/:uuid", controllers.GetCompanySMTPProfiles)
+		v1.DELETE("/company-smtp-profile/:uuid", controllers.DeleteCompanySMTPProfile)
+	}
+}
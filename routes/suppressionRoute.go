@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/farhan-nahid/email-service/controllers"
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/gin-gonic/gin"
+)
+
+func SuppressionRoute(router *gin.Engine) {
+	v1 := router.Group("/api/v1", middleware.Authenticate(), middleware.RateLimit())
+	{
+		v1.POST("/suppression", middleware.RequireScope(models.ScopeEmailWrite), middleware.BindAndValidate[models.Suppression](), controllers.CreateSuppression)
+		v1.GET("/suppression", middleware.RequireScope(models.ScopeEmailRead), controllers.GetSuppressions)
+		v1.GET("/suppression/:id", middleware.RequireScope(models.ScopeEmailRead), controllers.GetSuppressionByID)
+		v1.PATCH("/suppression/:id", middleware.RequireScope(models.ScopeEmailWrite), controllers.UpdateSuppressionByID)
+		v1.DELETE("/suppression/:id", middleware.RequireScope(models.ScopeEmailWrite), controllers.DeleteSuppressionByID)
+	}
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"github.com/farhan-nahid/email-service/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+func TrackingRoute(router *gin.Engine) {
+	// Public URLs embedded in emails, the open pixel token carries a .gif suffix for mail clients
+	router.GET("/t/o/:token", controllers.TrackOpen)
+	router.GET("/t/c/:token", controllers.TrackClick)
+}
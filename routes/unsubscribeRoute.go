@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/farhan-nahid/email-service/controllers"
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func UnsubscribeRoute(router *gin.Engine) {
+	// Public links embedded in emails
+	router.GET("/unsubscribe/:token", controllers.ShowUnsubscribe)
+	router.POST("/unsubscribe/:token", controllers.Unsubscribe)
+
+	v1 := router.Group("/api/v1", middleware.Authenticate(), middleware.RateLimit(), middleware.RequireAdmin())
+	{
+		v1.GET("/unsubscribe-preference", controllers.GetUnsubscribePreferences)
+		v1.DELETE("/unsubscribe-preference/:id", controllers.DeleteUnsubscribePreference)
+	}
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/farhan-nahid/email-service/controllers"
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/gin-gonic/gin"
+)
+
+func WebhookSubscriptionRoute(router *gin.Engine) {
+	v1 := router.Group("/api/v1", middleware.Authenticate(), middleware.RateLimit())
+	{
+		v1.POST("/webhook-subscription", middleware.RequireScope(models.ScopeEmailWrite), middleware.BindAndValidate[models.WebhookSubscription](), controllers.CreateWebhookSubscription)
+		v1.GET("/webhook-subscription", middleware.RequireScope(models.ScopeEmailRead), controllers.GetWebhookSubscriptions)
+		v1.GET("/webhook-subscription/:id", middleware.RequireScope(models.ScopeEmailRead), controllers.GetWebhookSubscriptionByID)
+		v1.DELETE("/webhook-subscription/:id", middleware.RequireScope(models.ScopeEmailWrite), controllers.DeleteWebhookSubscriptionByID)
+		v1.GET("/webhook-subscription/:id/deliveries", middleware.RequireScope(models.ScopeEmailRead), controllers.GetWebhookDeliveries)
+	}
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/farhan-nahid/email-service/controllers"
+	"github.com/farhan-nahid/email-service/middleware"
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/gin-gonic/gin"
+)
+
+func WebsiteRoute(router *gin.Engine) {
+	v1 := router.Group("/api/v1", middleware.Authenticate(), middleware.RateLimit())
+	{
+		v1.GET("/website", controllers.GetWebsites)
+		v1.GET("/website/:code", controllers.GetWebsiteByCode)
+
+		// Branding is edited with the template:write scope, removing a website from the shared
+		// registry is left to admins
+		v1.POST("/website", middleware.RequireScope(models.ScopeTemplateWrite), middleware.BindAndValidate[models.WebsiteConfig](), controllers.CreateWebsite)
+		v1.PATCH("/website/:code", middleware.RequireScope(models.ScopeTemplateWrite), controllers.UpdateWebsiteByCode)
+		v1.DELETE("/website/:code", middleware.RequireAdmin(), controllers.DeleteWebsiteByCode)
+	}
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// apiKeyPrefix makes keys recognizable in logs and secret scanners
+const apiKeyPrefix = "esk_"
+
+// GenerateAPIKey returns a new key and the lookup prefix embedded in it
+func GenerateAPIKey() (key string, prefix string, err error) {
+	random := make([]byte, 36)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", err
+	}
+
+	prefix = hex.EncodeToString(random[:4])
+	return apiKeyPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(random[4:]), prefix, nil
+}
+
+// APIKeyPrefix extracts the lookup prefix from a key
+func APIKeyPrefix(key string) (string, bool) {
+	rest, found := strings.CutPrefix(key, apiKeyPrefix)
+	if !found {
+		return "", false
+	}
+
+	prefix, _, found := strings.Cut(rest, "_")
+	return prefix, found && prefix != ""
+}
+
+// HashAPIKey returns the hash stored for a key, keys are random so a fast hash is enough
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
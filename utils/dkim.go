@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/emersion/go-msgauth/dkim"
+	"github.com/farhan-nahid/email-service/config"
+)
+
+// dkimHeaderKeys are the headers covered by the signature, see RFC 6376 section 5.4.1
+var dkimHeaderKeys = []string{
+	"From", "Reply-To", "Subject", "Date", "To", "Cc", "Message-ID",
+	"MIME-Version", "Content-Type", "List-Unsubscribe", "List-Unsubscribe-Post",
+}
+
+// DKIMKey is the selector and PEM encoded private key used to sign mail from a domain
+type DKIMKey struct {
+	Domain     string
+	Selector   string
+	PrivateKey string
+}
+
+// DKIMKeyFromConfig returns the key configured through DKIM_DOMAIN, DKIM_SELECTOR and
+// DKIM_PRIVATE_KEY_FILE when it matches the given domain
+func DKIMKeyFromConfig(cfg config.DKIMConfig, domain string) (*DKIMKey, error) {
+	if !strings.EqualFold(cfg.Domain, domain) || cfg.PrivateKeyFile == "" {
+		return nil, nil
+	}
+
+	privateKey, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DKIMKey{Domain: domain, Selector: cfg.Selector, PrivateKey: string(privateKey)}, nil
+}
+
+// ParseDKIMPrivateKey parses a PEM encoded RSA or Ed25519 private key
+func ParseDKIMPrivateKey(privateKey string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	}
+	return nil, errors.New("private key must be RSA or Ed25519")
+}
+
+// DKIMDNSRecord returns the TXT record value to publish at <selector>._domainkey.<domain>
+func DKIMDNSRecord(privateKey string) (string, error) {
+	signer, err := ParseDKIMPrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	switch public := signer.Public().(type) {
+	case ed25519.PublicKey:
+		return "v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(public), nil
+	default:
+		der, err := x509.MarshalPKIXPublicKey(public)
+		if err != nil {
+			return "", err
+		}
+		return "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der), nil
+	}
+}
+
+// SignDKIM returns the message with a DKIM-Signature header prepended
+func SignDKIM(message []byte, key *DKIMKey) ([]byte, error) {
+	signer, err := ParseDKIMPrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var signed bytes.Buffer
+	err = dkim.Sign(&signed, bytes.NewReader(message), &dkim.SignOptions{
+		Domain:                 key.Domain,
+		Selector:               key.Selector,
+		Signer:                 signer,
+		HeaderCanonicalization: dkim.CanonicalizationRelaxed,
+		BodyCanonicalization:   dkim.CanonicalizationRelaxed,
+		HeaderKeys:             dkimHeaderKeys,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return signed.Bytes(), nil
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// verpPattern extracts the email UUID from a VERP return path like bounces+<uuid>@example.com
+var verpPattern = regexp.MustCompile(`\+([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})@`)
+
+// DSNRecipient holds the per recipient fields of a delivery status notification
+type DSNRecipient struct {
+	FinalRecipient string
+	Action         string
+	Status         string
+	DiagnosticCode string
+}
+
+// IsHardBounce reports whether delivery permanently failed
+func (r DSNRecipient) IsHardBounce() bool {
+	return strings.EqualFold(r.Action, "failed") && strings.HasPrefix(r.Status, "5")
+}
+
+// DSN is the parsed form of an RFC 3464 delivery status notification
+type DSN struct {
+	OriginalMessageID string
+	VERPEmailUUID     string
+	Recipients        []DSNRecipient
+}
+
+// ReturnPath builds the VERP envelope sender for an email, it is empty without a bounce domain
+func ReturnPath(bounceDomain string, emailUUID string) string {
+	if bounceDomain == "" {
+		return ""
+	}
+	return "bounces+" + emailUUID + "@" + bounceDomain
+}
+
+// ParseDSN reads a raw multipart/report delivery status notification
+func ParseDSN(r io.Reader) (*DSN, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	dsn := &DSN{}
+	for _, header := range []string{"To", "Delivered-To", "X-Original-To"} {
+		if match := verpPattern.FindStringSubmatch(msg.Header.Get(header)); match != nil {
+			dsn.VERPEmailUUID = strings.ToLower(match[1])
+			break
+		}
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || !strings.EqualFold(mediaType, "multipart/report") {
+		return nil, errors.New("message is not a multipart/report")
+	}
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch strings.ToLower(partType) {
+		case "message/delivery-status":
+			if dsn.Recipients, err = parseDeliveryStatus(part); err != nil {
+				return nil, err
+			}
+		case "message/rfc822", "text/rfc822-headers":
+			// The returned message, only its headers are needed
+			headers, err := textproto.NewReader(bufio.NewReader(part)).ReadMIMEHeader()
+			if err != nil && len(headers) == 0 {
+				continue
+			}
+			dsn.OriginalMessageID = strings.TrimSpace(headers.Get("Message-Id"))
+		}
+	}
+
+	if len(dsn.Recipients) == 0 {
+		return nil, errors.New("message has no delivery status")
+	}
+	return dsn, nil
+}
+
+// parseDeliveryStatus reads the per message block followed by one block per recipient
+func parseDeliveryStatus(r io.Reader) ([]DSNRecipient, error) {
+	reader := textproto.NewReader(bufio.NewReader(r))
+
+	// Skip the per message fields
+	if _, err := reader.ReadMIMEHeader(); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	var recipients []DSNRecipient
+	for {
+		fields, err := reader.ReadMIMEHeader()
+		if len(fields) > 0 {
+			recipients = append(recipients, DSNRecipient{
+				FinalRecipient: dsnAddress(fields.Get("Final-Recipient")),
+				Action:         strings.TrimSpace(fields.Get("Action")),
+				Status:         strings.TrimSpace(fields.Get("Status")),
+				DiagnosticCode: strings.TrimSpace(fields.Get("Diagnostic-Code")),
+			})
+		}
+		if err == io.EOF {
+			return recipients, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// dsnAddress strips the address type from fields like "rfc822; user@example.com"
+func dsnAddress(field string) string {
+	if _, address, found := strings.Cut(field, ";"); found {
+		return strings.TrimSpace(address)
+	}
+	return strings.TrimSpace(field)
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// dsnMessage builds a multipart/report bounce with the delivery status and returned headers
+func dsnMessage(to, status, returned string) string {
+	return "To: " + to + "\r\n" +
+		"Content-Type: multipart/report; report-type=delivery-status; boundary=\"b1\"\r\n" +
+		"\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"Delivery failed.\r\n" +
+		"--b1\r\n" +
+		"Content-Type: message/delivery-status\r\n" +
+		"\r\n" +
+		"Reporting-MTA: dns; mx.example.net\r\n" +
+		"\r\n" +
+		status +
+		"--b1\r\n" +
+		"Content-Type: text/rfc822-headers\r\n" +
+		"\r\n" +
+		returned +
+		"\r\n" +
+		"--b1--\r\n"
+}
+
+func TestParseDSN(t *testing.T) {
+	const emailUUID = "3f0c6a1e-2b4d-4c8e-9f1a-7d6e5c4b3a29"
+
+	tests := []struct {
+		name       string
+		message    string
+		verpUUID   string
+		messageID  string
+		recipients []DSNRecipient
+		hard       []bool
+		err        string
+	}{
+		{
+			name: "hard bounce with VERP address",
+			message: dsnMessage("bounces+"+strings.ToUpper(emailUUID)+"@bounces.example.com",
+				"Final-Recipient: rfc822; bob@example.org\r\nAction: failed\r\nStatus: 5.1.1\r\nDiagnostic-Code: smtp; 550 5.1.1 user unknown\r\n\r\n",
+				"Message-Id: <"+emailUUID+"@example.com>\r\n"),
+			verpUUID:   emailUUID,
+			messageID:  "<" + emailUUID + "@example.com>",
+			recipients: []DSNRecipient{{"bob@example.org", "failed", "5.1.1", "smtp; 550 5.1.1 user unknown"}},
+			hard:       []bool{true},
+		},
+		{
+			name: "soft bounce and several recipients",
+			message: dsnMessage("postmaster@example.com",
+				"Final-Recipient: rfc822; bob@example.org\r\nAction: delayed\r\nStatus: 4.2.2\r\n\r\n"+
+					"Final-Recipient: rfc822; carol@example.org\r\nAction: failed\r\nStatus: 5.2.1\r\n\r\n",
+				"Subject: hello\r\n"),
+			recipients: []DSNRecipient{{"bob@example.org", "delayed", "4.2.2", ""}, {"carol@example.org", "failed", "5.2.1", ""}},
+			hard:       []bool{false, true},
+		},
+		{
+			name:    "not a report",
+			message: "To: postmaster@example.com\r\nContent-Type: text/plain\r\n\r\nOut of office\r\n",
+			err:     "not a multipart/report",
+		},
+		{
+			name:    "report without recipients",
+			message: dsnMessage("postmaster@example.com", "", "Subject: hello\r\n"),
+			err:     "no delivery status",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dsn, err := ParseDSN(strings.NewReader(test.message))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected an error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsing: %v", err)
+			}
+
+			if dsn.VERPEmailUUID != test.verpUUID || dsn.OriginalMessageID != test.messageID {
+				t.Errorf("expected uuid %q and message id %q, got %q and %q", test.verpUUID, test.messageID, dsn.VERPEmailUUID, dsn.OriginalMessageID)
+			}
+			if len(dsn.Recipients) != len(test.recipients) {
+				t.Fatalf("expected %d recipients, got %+v", len(test.recipients), dsn.Recipients)
+			}
+			for i, recipient := range dsn.Recipients {
+				if recipient != test.recipients[i] {
+					t.Errorf("expected recipient %+v, got %+v", test.recipients[i], recipient)
+				}
+				if recipient.IsHardBounce() != test.hard[i] {
+					t.Errorf("expected hard bounce %v for %+v", test.hard[i], recipient)
+				}
+			}
+		})
+	}
+}
+
+func TestReturnPath(t *testing.T) {
+	if path := ReturnPath("", "3f0c6a1e-2b4d-4c8e-9f1a-7d6e5c4b3a29"); path != "" {
+		t.Errorf("expected no return path without a bounce domain, got %q", path)
+	}
+	if path := ReturnPath("bounces.example.com", "3f0c6a1e-2b4d-4c8e-9f1a-7d6e5c4b3a29"); path != "bounces+3f0c6a1e-2b4d-4c8e-9f1a-7d6e5c4b3a29@bounces.example.com" {
+		t.Errorf("unexpected return path %q", path)
+	}
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// encryptedPrefix marks values produced by Encrypt so plain values written before
+// encryption was enabled can still be read
+const encryptedPrefix = "enc:v1:"
+
+type encryptionKeyKey struct{}
+
+// WithEncryptionKey stores the ENCRYPTION_KEY for values encrypted while the context is used
+func WithEncryptionKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, encryptionKeyKey{}, key)
+}
+
+// EncryptionKey returns the key stored by WithEncryptionKey, or an empty string
+func EncryptionKey(ctx context.Context) string {
+	key, _ := ctx.Value(encryptionKeyKey{}).(string)
+	return key
+}
+
+// encryptionKey decodes the base64 encoded 32 byte ENCRYPTION_KEY
+func encryptionKey(encoded string) ([]byte, error) {
+	if encoded == "" {
+		return nil, errors.New("ENCRYPTION_KEY is not set")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("ENCRYPTION_KEY must be 32 bytes encoded as base64")
+	}
+	return key, nil
+}
+
+func newGCM(encodedKey string) (cipher.AEAD, error) {
+	key, err := encryptionKey(encodedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt encrypts the given value with AES-256-GCM using the ENCRYPTION_KEY
+func Encrypt(key, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt, values without the encrypted prefix are returned as is
+func Decrypt(key, value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	otherKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("o", 32)))
+
+	for _, plaintext := range []string{"smtp-password", "", "ünïcödé 🔑", strings.Repeat("x", 4096)} {
+		encrypted, err := Encrypt(key, plaintext)
+		if err != nil {
+			t.Fatalf("encrypting %q: %v", plaintext, err)
+		}
+		if !strings.HasPrefix(encrypted, encryptedPrefix) || (plaintext != "" && strings.Contains(encrypted, plaintext)) {
+			t.Fatalf("unexpected ciphertext %q", encrypted)
+		}
+
+		again, _ := Encrypt(key, plaintext)
+		if again == encrypted {
+			t.Fatalf("encrypting %q twice gave the same ciphertext, the nonce must be random", plaintext)
+		}
+
+		decrypted, err := Decrypt(key, encrypted)
+		if err != nil || decrypted != plaintext {
+			t.Fatalf("expected %q back, got %q: %v", plaintext, decrypted, err)
+		}
+		if _, err := Decrypt(otherKey, encrypted); err == nil {
+			t.Fatalf("decrypting %q with another key must fail", plaintext)
+		}
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	encrypted, _ := Encrypt(key, "smtp-password")
+	sealed, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(encrypted, encryptedPrefix))
+	sealed[len(sealed)-1] ^= 1
+
+	tests := []struct {
+		name      string
+		key       string
+		value     string
+		plaintext string
+		fails     bool
+	}{
+		{"plain values written before encryption", key, "legacy-password", "legacy-password", false},
+		{"plain values without a key", "", "legacy-password", "legacy-password", false},
+		{"tampered ciphertext", key, encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), "", true},
+		{"truncated ciphertext", key, encryptedPrefix + base64.StdEncoding.EncodeToString(sealed[:8]), "", true},
+		{"not base64", key, encryptedPrefix + "%%%", "", true},
+		{"missing key", "", encrypted, "", true},
+		{"short key", base64.StdEncoding.EncodeToString([]byte("short")), encrypted, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plaintext, err := Decrypt(test.key, test.value)
+			if test.fails {
+				if err == nil {
+					t.Fatalf("expected decrypting to fail, got %q", plaintext)
+				}
+				return
+			}
+			if err != nil || plaintext != test.plaintext {
+				t.Fatalf("expected %q, got %q: %v", test.plaintext, plaintext, err)
+			}
+		})
+	}
+
+	if _, err := Encrypt("", "smtp-password"); err == nil {
+		t.Error("expected encrypting without ENCRYPTION_KEY to fail")
+	}
+}
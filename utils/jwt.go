@@ -0,0 +1,284 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/farhan-nahid/email-service/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidJWT is returned when a bearer token is not a valid JWT from the identity provider
+var ErrInvalidJWT = errors.New("invalid or expired token")
+
+const (
+	// jwksRefreshInterval is how long fetched signing keys are trusted before refetching
+	jwksRefreshInterval = time.Hour
+	// jwksMinRefetchInterval limits refetches triggered by unknown key ids
+	jwksMinRefetchInterval = time.Minute
+)
+
+// JWTClaims are the claims the service authorizes callers with
+type JWTClaims struct {
+	Subject   string
+	Roles     []string
+	Companies []string
+}
+
+// JWTEnabled reports whether JWT_JWKS_URL or JWT_STATIC_KEY is configured
+func JWTEnabled(cfg config.AuthConfig) bool {
+	return cfg.JWKSURL != "" || cfg.JWTStaticKey != ""
+}
+
+// LooksLikeJWT tells JWTs apart from API keys, which never contain dots
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// VerifyJWT validates the token signature, expiry, issuer (JWT_ISSUER) and audience (JWT_AUDIENCE),
+// then reads roles and companies from JWT_ROLES_CLAIM and JWT_COMPANIES_CLAIM
+func VerifyJWT(cfg config.AuthConfig, token string) (*JWTClaims, error) {
+	keyFunc, methods, err := jwtKeyFunc(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired(), jwt.WithLeeway(30 * time.Second)}
+	if cfg.JWTIssuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.JWTIssuer))
+	}
+	if cfg.JWTAudience != "" {
+		options = append(options, jwt.WithAudience(cfg.JWTAudience))
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, keyFunc, options...); err != nil {
+		return nil, ErrInvalidJWT
+	}
+
+	subject, _ := claims.GetSubject()
+	return &JWTClaims{
+		Subject:   subject,
+		Roles:     claimStrings(claims, cfg.JWTRolesClaim),
+		Companies: claimStrings(claims, cfg.JWTCompaniesClaim),
+	}, nil
+}
+
+// jwtKeyFunc picks the signing keys, a JWKS endpoint in production or a static key for local testing
+func jwtKeyFunc(cfg config.AuthConfig) (jwt.Keyfunc, []string, error) {
+	if jwksURL := cfg.JWKSURL; jwksURL != "" {
+		keyFunc := func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return defaultJWKS(jwksURL).key(kid)
+		}
+		return keyFunc, []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}, nil
+	}
+
+	staticKey := cfg.JWTStaticKey
+	if staticKey == "" {
+		return nil, nil, errors.New("JWT authentication is not configured")
+	}
+
+	// A PEM public key verifies asymmetric tokens, anything else is a shared HMAC secret
+	if block, _ := pem.Decode([]byte(staticKey)); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid JWT_STATIC_KEY: %w", err)
+		}
+		keyFunc := func(*jwt.Token) (interface{}, error) { return key, nil }
+		return keyFunc, []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}, nil
+	}
+	keyFunc := func(*jwt.Token) (interface{}, error) { return []byte(staticKey), nil }
+	return keyFunc, []string{"HS256", "HS384", "HS512"}, nil
+}
+
+// claimStrings reads a string or string list claim, dotted names reach into nested objects
+// such as Keycloak's realm_access.roles
+func claimStrings(claims jwt.MapClaims, name string) []string {
+	var value interface{} = map[string]interface{}(claims)
+	for _, part := range strings.Split(name, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[part]
+	}
+
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// ------------------- JWKS ------------------- //
+
+type jwks struct {
+	url       string
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+var (
+	jwksCache   = make(map[string]*jwks)
+	jwksCacheMu sync.Mutex
+)
+
+func defaultJWKS(url string) *jwks {
+	jwksCacheMu.Lock()
+	defer jwksCacheMu.Unlock()
+
+	if set, ok := jwksCache[url]; ok {
+		return set
+	}
+	set := &jwks{url: url}
+	jwksCache[url] = set
+	return set
+}
+
+// key returns the signing key with the id, refetching the set when it is stale or the key
+// was rotated in since the last fetch
+func (s *jwks) key(kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[kid]
+	stale := time.Since(s.fetchedAt) > jwksRefreshInterval
+	if ok && !stale {
+		return key, nil
+	}
+	if stale || time.Since(s.fetchedAt) > jwksMinRefetchInterval {
+		if err := s.fetch(); err != nil && s.keys == nil {
+			return nil, err
+		}
+	}
+
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (s *jwks) fetch() error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	response, err := client.Get(s.url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching JWKS returned status %d", response.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(response.Body, 1024*1024)).Decode(&set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped so one odd key does not break the whole set
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	return nil
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64URLInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64URLInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64URLInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64URLInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func base64URLInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
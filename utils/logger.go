@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/farhan-nahid/email-service/config"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ------------------- Structured Logging ------------------- //
+
+type requestIDKey struct{}
+
+// WithRequestID stores the request id so every log written with the context carries it
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request id stored in the context, or an empty string
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// NewLogger creates the structured logger, LOG_LEVEL is debug, info, warn or error and
+// LOG_FORMAT is json (default) or text
+func NewLogger(cfg config.LogConfig) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		level = slog.LevelInfo
+	}
+
+	options := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+	var handler slog.Handler = slog.NewJSONHandler(os.Stdout, options)
+	if strings.EqualFold(cfg.Format, "text") {
+		handler = slog.NewTextHandler(os.Stdout, options)
+	}
+
+	return slog.New(contextHandler{handler})
+}
+
+// contextHandler adds the request id and trace of the context to each record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()), slog.String("span_id", spanContext.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// ------------------- Redaction ------------------- //
+
+// addressKeys hold email addresses, only their first letter and domain are logged
+var addressKeys = map[string]bool{"recipient": true, "receiver": true, "to": true, "address": true, "email": true}
+
+// secretKeyPattern matches attribute and payload keys whose values are never logged
+var secretKeyPattern = regexp.MustCompile(`(?i)pass|secret|token|api_?key|otp|authorization|signature|link`)
+
+// emailPattern finds addresses embedded in free text such as error messages
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	key := strings.ToLower(attr.Key)
+
+	switch {
+	case key == "payload":
+		return slog.Any(attr.Key, RedactPayload(attr.Value.Any()))
+	case addressKeys[key]:
+		return slog.String(attr.Key, RedactEmail(attr.Value.String()))
+	case secretKeyPattern.MatchString(key):
+		return slog.String(attr.Key, "[REDACTED]")
+	case attr.Value.Kind() == slog.KindString:
+		return slog.String(attr.Key, emailPattern.ReplaceAllStringFunc(attr.Value.String(), RedactEmail))
+	case attr.Value.Kind() == slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			return slog.String(attr.Key, emailPattern.ReplaceAllStringFunc(err.Error(), RedactEmail))
+		}
+	}
+	return attr
+}
+
+// RedactEmail keeps the first character of the local part and the domain, "jane@example.com"
+// becomes "j***@example.com"
+func RedactEmail(address string) string {
+	return emailPattern.ReplaceAllStringFunc(address, func(match string) string {
+		at := strings.LastIndex(match, "@")
+		return match[:1] + "***" + match[at:]
+	})
+}
+
+// RedactPayload copies a template payload with secret looking values replaced
+func RedactPayload(payload interface{}) interface{} {
+	switch value := payload.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			if secretKeyPattern.MatchString(key) {
+				redacted[key] = "[REDACTED]"
+			} else {
+				redacted[key] = RedactPayload(item)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+		for i, item := range value {
+			redacted[i] = RedactPayload(item)
+		}
+		return redacted
+	case string:
+		return RedactEmail(value)
+	}
+	return payload
+}
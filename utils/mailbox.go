@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ------------------- Development Mailbox ------------------- //
+
+// MailboxAttachment describes an attachment of a captured message, the content is served
+// separately
+type MailboxAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+	Content     []byte `json:"-"`
+}
+
+// MailboxMessage is a message captured by the mailbox with its decoded parts
+type MailboxMessage struct {
+	ID           string              `json:"id"`
+	ReceivedAt   time.Time           `json:"received_at"`
+	EnvelopeFrom string              `json:"envelope_from"`
+	EnvelopeTo   []string            `json:"envelope_to"`
+	SMTPHost     string              `json:"smtp_host"`
+	From         string              `json:"from"`
+	To           string              `json:"to"`
+	Subject      string              `json:"subject"`
+	Headers      map[string][]string `json:"headers"`
+	HTML         string              `json:"html"`
+	Text         string              `json:"text"`
+	Attachments  []MailboxAttachment `json:"attachments"`
+	Raw          []byte              `json:"-"`
+}
+
+// Mailbox is a transport for development, it keeps the most recent messages in memory
+// instead of delivering them so they can be inspected at /dev/mailbox
+type Mailbox struct {
+	mu       sync.RWMutex
+	messages []*MailboxMessage
+	capacity int
+}
+
+// NewMailbox creates a mailbox keeping up to capacity messages
+func NewMailbox(capacity int) *Mailbox {
+	return &Mailbox{capacity: capacity}
+}
+
+// Send captures the messages, the SMTP configuration is only recorded
+func (m *Mailbox) Send(ctx context.Context, config SMTPConfig, envelopes ...*Envelope) error {
+	for _, envelope := range envelopes {
+		var raw bytes.Buffer
+		if _, err := envelope.Message.WriteTo(&raw); err != nil {
+			return err
+		}
+
+		message, err := parseMailboxMessage(raw.Bytes())
+		if err != nil {
+			return err
+		}
+		message.ID = uuid.NewString()
+		message.ReceivedAt = time.Now()
+		message.EnvelopeFrom = envelope.From
+		message.EnvelopeTo = envelope.To
+		message.SMTPHost = config.Host
+
+		m.mu.Lock()
+		m.messages = append(m.messages, message)
+		if len(m.messages) > m.capacity {
+			m.messages = m.messages[len(m.messages)-m.capacity:]
+		}
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+// Messages returns the captured messages, newest first
+func (m *Mailbox) Messages() []*MailboxMessage {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	messages := make([]*MailboxMessage, 0, len(m.messages))
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		messages = append(messages, m.messages[i])
+	}
+	return messages
+}
+
+// Message returns the captured message with the id
+func (m *Mailbox) Message(id string) (*MailboxMessage, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, message := range m.messages {
+		if message.ID == id {
+			return message, true
+		}
+	}
+	return nil, false
+}
+
+// Clear removes every captured message
+func (m *Mailbox) Clear() {
+	m.mu.Lock()
+	m.messages = nil
+	m.mu.Unlock()
+}
+
+// Close implements Transport, the mailbox holds no connections
+func (m *Mailbox) Close() {}
+
+// parseMailboxMessage decodes the headers and collects the HTML, text and attachment parts
+func parseMailboxMessage(raw []byte) (*MailboxMessage, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := new(mime.WordDecoder)
+	decode := func(value string) string {
+		if decoded, err := decoder.DecodeHeader(value); err == nil {
+			return decoded
+		}
+		return value
+	}
+
+	message := &MailboxMessage{
+		From:    decode(msg.Header.Get("From")),
+		To:      decode(msg.Header.Get("To")),
+		Subject: decode(msg.Header.Get("Subject")),
+		Headers: make(map[string][]string, len(msg.Header)),
+		Raw:     raw,
+	}
+	for name, values := range msg.Header {
+		for _, value := range values {
+			message.Headers[name] = append(message.Headers[name], decode(value))
+		}
+	}
+
+	if err := collectMailboxParts(message, textproto.MIMEHeader(msg.Header), msg.Body); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// collectMailboxParts walks every multipart level, the first HTML and text parts without a
+// file name are the bodies and every other part is an attachment
+func collectMailboxParts(message *MailboxMessage, header textproto.MIMEHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := collectMailboxParts(message, part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := dispositionParams["filename"]
+	switch {
+	case disposition != "attachment" && filename == "" && mediaType == "text/html" && message.HTML == "":
+		message.HTML = string(content)
+	case disposition != "attachment" && filename == "" && mediaType == "text/plain" && message.Text == "":
+		message.Text = string(content)
+	default:
+		message.Attachments = append(message.Attachments, MailboxAttachment{
+			Filename:    filename,
+			ContentType: mediaType,
+			Size:        len(content),
+			Content:     content,
+		})
+	}
+	return nil
+}
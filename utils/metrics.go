@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ------------------- Prometheus Metrics ------------------- //
+
+var (
+	// EmailsCreated counts send requests that passed validation
+	EmailsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_service_emails_created_total",
+		Help: "Email send requests accepted for processing.",
+	}, []string{"website", "source"})
+
+	// EmailStatusChanges counts emails reaching a status, including later bounces and deliveries
+	EmailStatusChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_service_email_status_total",
+		Help: "Emails that reached a status.",
+	}, []string{"website", "source", "status"})
+
+	SMTPSendDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "email_service_smtp_send_duration_seconds",
+		Help:    "Time to hand a message to the SMTP server, including dialing.",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"host", "result"})
+
+	SMTPRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_service_smtp_retries_total",
+		Help: "Messages resent after a pooled SMTP connection broke.",
+	}, []string{"host"})
+
+	TemplateRenderDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "email_service_template_render_duration_seconds",
+		Help:    "Time to parse and execute an email template.",
+		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"template"})
+
+	WebhookQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "email_service_webhook_queue_depth",
+		Help: "Webhook deliveries waiting to be sent.",
+	})
+
+	WebhookAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_service_webhook_attempts_total",
+		Help: "Webhook delivery attempts by result, retry means another attempt is scheduled.",
+	}, []string{"result"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "email_service_http_request_duration_seconds",
+		Help:    "HTTP request latency per route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	DBErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_service_db_errors_total",
+		Help: "Database operations that returned an error other than record not found.",
+	}, []string{"operation"})
+)
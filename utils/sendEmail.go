@@ -2,97 +2,207 @@ package utils
 
 import (
 	"bytes"
-	"html/template"
+	"context"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/mail"
 	"os"
-	"strconv"
+	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 	"gopkg.in/gomail.v2"
 )
 
 type Data struct {
 	Name     string
 	Sender   string
+	ReplyTo  string
 	Receiver string
 	Subject  string
 	Payload  interface{}
+	Branding Branding
+	SMTP     SMTPConfig
+	DKIM     *DKIMKey
+
+	// MessageID is set as the Message-ID header, ReturnPath overrides the envelope sender
+	MessageID  string
+	ReturnPath string
+
+	// Headers are extra headers added to the message
+	Headers map[string]string
+
+	// TrackClick rewrites trackable links to the redirect URLs it returns when set
+	TrackClick func(link string) (string, bool)
+
+	// OpenPixelURL injects an open tracking pixel into the HTML body when set
+	OpenPixelURL string
+
+	// UnsubscribeURL adds List-Unsubscribe headers when set
+	UnsubscribeURL string
+}
+
+// Branding holds the website assets that templates can use
+type Branding struct {
+	DisplayName  string
+	LogoURL      string
+	PrimaryColor string
+	FooterText   string
 }
 
-func SendEmail(data Data, templatePath string) (error) {
-	log.Println("Sending email to: ", data.Receiver)
+// TemplateData is the value templates are executed with
+type TemplateData struct {
+	Name           string
+	Payload        interface{}
+	Branding       Branding
+	UnsubscribeURL string
+}
+
+// SendEmail renders the named template of the registry and sends the message, ctx carries the
+// request id for logging and the trace the send spans belong to
+func (mailer *Mailer) SendEmail(ctx context.Context, data Data, templateName string) (err error) {
+	ctx, span := StartSpan(ctx, "email.send", attribute.String("email.template", templateName))
+	defer func() { EndSpan(span, err) }()
+
+	logger := mailer.Logger.With(slog.String("recipient", data.Receiver), slog.String("template", templateName))
+	logger.InfoContext(ctx, "sending email")
 	
 	var body bytes.Buffer
-	wd, err := os.Getwd()
-	if err != nil {
-		return err
-	}
-	
-	log.Println("Working directory: ", wd)
-	t, err := template.ParseFiles(wd + templatePath)
+	renderStart := time.Now()
+	_, renderSpan := StartSpan(ctx, "email.render", attribute.String("email.template", templateName))
+	t, err := mailer.Templates.Template(templateName)
 
 	if err != nil {
+		EndSpan(renderSpan, err)
 		return err
 	}
 
 	// Execute the template with the provided data
-	t.Execute(&body, struct{ Name string }{Name: data.Name})
+	if err := t.Execute(&body, TemplateData{Name: data.Name, Payload: data.Payload, Branding: data.Branding, UnsubscribeURL: data.UnsubscribeURL}); err != nil {
+		EndSpan(renderSpan, err)
+		return err
+	}
+	EndSpan(renderSpan, nil)
+	TemplateRenderDuration.WithLabelValues(templateName).Observe(time.Since(renderStart).Seconds())
 
- 	log.Println("Attempting to send email body")
+ 	logger.DebugContext(ctx, "template rendered", slog.Duration("duration", time.Since(renderStart)))
 	// Construct the email
 	m := gomail.NewMessage()
 	m.SetHeader("From", data.Sender)
 	m.SetHeader("To", data.Receiver)
+	if data.MessageID != "" {
+		m.SetHeader("Message-ID", data.MessageID)
+	}
+	for name, value := range data.Headers {
+		m.SetHeader(name, value)
+	}
+	if data.ReplyTo != "" {
+		m.SetHeader("Reply-To", data.ReplyTo)
+	}
+	if data.UnsubscribeURL != "" {
+		// One-click unsubscribe, see RFC 8058
+		m.SetHeader("List-Unsubscribe", "<"+data.UnsubscribeURL+">")
+		m.SetHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
 	m.SetHeader("Subject", data.Subject)
 	// invoiceLink := ""
 	// Set the email body as HTML content
-	m.SetBody("text/html", body.String())
+	html := body.String()
+	if data.TrackClick != nil {
+		html = RewriteLinks(html, data.TrackClick)
+	}
+	if data.OpenPixelURL != "" {
+		html = InjectOpenPixel(html, data.OpenPixelURL)
+	}
+	m.SetBody("text/html", html)
 
 	if payload, ok := data.Payload.(map[string]interface{}); ok {
 		if invoiceLink, ok := payload["invoiceLink"].(string); ok && invoiceLink != "" {
-			log.Println("Attaching invoice to email")
-			// Example of downloading a file and attaching it
-			response, err := http.Get(invoiceLink)
-			if err != nil {
-				return err
-			}
-			defer response.Body.Close()
-
-			// Create a temporary file to save the content
-			tmpFile, err := os.Create("invoice.pdf")
+			logger.DebugContext(ctx, "attaching invoice")
+			path, err := downloadAttachment(ctx, invoiceLink, "invoice.pdf")
 			if err != nil {
 				return err
 			}
-			
-			defer tmpFile.Close()
-
-			// Write the content from the response to the temporary file
-			_, err = io.Copy(tmpFile, response.Body)
-			if err != nil {
-				return err
-			}
-			
+			defer os.Remove(path)
 
 			// Attach the downloaded file to the email
-			m.Attach("invoice.pdf")
+			m.Attach(path, gomail.Rename("invoice.pdf"))
 		}
 	}
 
-	port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	from, err := mail.ParseAddress(data.Sender)
 	if err != nil {
 		return err
 	}
 
+	envelope := &Envelope{From: from.Address, To: []string{data.Receiver}, Message: m}
+	if data.ReturnPath != "" {
+		envelope.From = data.ReturnPath
+	}
 
-	log.Println("Attempting to send email")
-	// Create the dialer and send the email
-	d := gomail.NewDialer(os.Getenv("SMTP_HOST"), port, os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASS"))
+	// Sign the rendered message when a DKIM key exists for the sender domain
+	if data.DKIM != nil {
+		var rendered bytes.Buffer
+		if _, err := m.WriteTo(&rendered); err != nil {
+			return err
+		}
 
-	// Send the email
-	if err := d.DialAndSend(m); err != nil {
+		signed, err := SignDKIM(rendered.Bytes(), data.DKIM)
+		if err != nil {
+			return err
+		}
+		envelope.Message = RawMessage(signed)
+	}
+
+	// Hand the email to the transport, a pooled SMTP connection for the resolved profile
+	sendStart := time.Now()
+	if err := mailer.Transport.Send(ctx, data.SMTP, envelope); err != nil {
+		SMTPSendDuration.WithLabelValues(data.SMTP.Host, "error").Observe(time.Since(sendStart).Seconds())
+		logger.ErrorContext(ctx, "sending email failed", slog.String("smtp_host", data.SMTP.Host), slog.Any("error", err))
 		return err
 	}
+	SMTPSendDuration.WithLabelValues(data.SMTP.Host, "ok").Observe(time.Since(sendStart).Seconds())
+	logger.InfoContext(ctx, "email sent", slog.String("smtp_host", data.SMTP.Host), slog.String("message_id", data.MessageID), slog.Duration("duration", time.Since(sendStart)))
 
 	return nil
 }
+
+// downloadAttachment saves the file at the URL to a temporary file and returns its path, the
+// caller removes it once the message is sent
+func downloadAttachment(ctx context.Context, url, name string) (path string, err error) {
+	ctx, span := StartSpan(ctx, "email.attachment", attribute.String("email.attachment", name))
+	defer func() { EndSpan(span, err) }()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("downloading attachment %s: %s", name, response.Status)
+	}
+
+	// Create a temporary file to save the content, concurrent sends each get their own
+	tmpFile, err := os.CreateTemp("", "attachment-*-"+name)
+	if err != nil {
+		return "", err
+	}
+	defer tmpFile.Close()
+
+	// Write the content from the response to the temporary file
+	if _, err = io.Copy(tmpFile, response.Body); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	return tmpFile.Name(), nil
+}
@@ -0,0 +1,302 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/farhan-nahid/email-service/config"
+	"gopkg.in/gomail.v2"
+)
+
+// TLS modes supported by SMTP profiles
+const (
+	TLSModeNone     = "NONE"
+	TLSModeStartTLS = "STARTTLS"
+	TLSModeTLS      = "TLS"
+)
+
+// Auth mechanisms supported by SMTP profiles, an empty mechanism picks one the server advertises
+const (
+	AuthNone    = "NONE"
+	AuthPlain   = "PLAIN"
+	AuthLogin   = "LOGIN"
+	AuthCRAMMD5 = "CRAM-MD5"
+)
+
+const smtpDialTimeout = 10 * time.Second
+
+// SMTPConfig holds everything needed to open an SMTP session
+type SMTPConfig struct {
+	Host          string
+	Port          int
+	Username      string
+	Password      string
+	TLSMode       string
+	AuthMechanism string
+}
+
+// DefaultSMTPConfig builds the SMTP configuration from the SMTP_* settings, without
+// SMTP_TLS_MODE port 465 uses implicit TLS and every other port upgrades with STARTTLS when
+// the server offers it
+func DefaultSMTPConfig(cfg config.SMTPConfig) (SMTPConfig, error) {
+	if cfg.Host == "" {
+		return SMTPConfig{}, errors.New("SMTP_HOST is not set")
+	}
+
+	tlsMode := cfg.TLSMode
+	if tlsMode == "" && cfg.Port == 465 {
+		tlsMode = TLSModeTLS
+	}
+
+	return SMTPConfig{
+		Host:          cfg.Host,
+		Port:          cfg.Port,
+		Username:      cfg.User,
+		Password:      cfg.Password,
+		TLSMode:       tlsMode,
+		AuthMechanism: cfg.AuthMechanism,
+	}, nil
+}
+
+// DialSMTP opens an authenticated SMTP session honouring the TLS mode and auth mechanism
+func DialSMTP(config SMTPConfig) (gomail.SendCloser, error) {
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	tlsConfig := &tls.Config{ServerName: config.Host}
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+
+	var conn net.Conn
+	var err error
+	if config.TLSMode == TLSModeTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if config.TLSMode == TLSModeStartTLS || config.TLSMode == "" {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				c.Close()
+				return nil, err
+			}
+		} else if config.TLSMode == TLSModeStartTLS {
+			c.Close()
+			return nil, errors.New("smtp server does not support STARTTLS")
+		}
+	}
+
+	if auth := smtpAuth(c, config); auth != nil {
+		if err := c.Auth(auth); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	return &smtpSender{conn: conn, client: c}, nil
+}
+
+// PingSMTP checks that the server greets, answers EHLO and NOOP within the context deadline,
+// it does not authenticate so it is cheap enough for readiness probes
+func PingSMTP(ctx context.Context, config SMTPConfig) error {
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+
+	var conn net.Conn
+	var err error
+	if config.TLSMode == TLSModeTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: config.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		return err
+	}
+	if err := c.Hello("localhost"); err != nil {
+		return err
+	}
+	if err := c.Noop(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// SMTPFailureReason describes why PingSMTP failed without the dial address or server reply,
+// the reason is safe to show to callers that must not learn internal hosts
+func SMTPFailureReason(err error) string {
+	var netErr net.Error
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	var tlsErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	var protoErr *textproto.Error
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		return "timeout"
+	case errors.As(err, &dnsErr):
+		return "host not found"
+	case errors.As(err, &tlsErr) || errors.As(err, &certErr):
+		return "tls handshake failed"
+	case errors.As(err, &protoErr):
+		return "unexpected server reply"
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return "connection refused"
+	}
+	return "unreachable"
+}
+
+// smtpAuth picks the auth implementation for the configured mechanism
+func smtpAuth(c *smtp.Client, config SMTPConfig) smtp.Auth {
+	if config.Username == "" || config.AuthMechanism == AuthNone {
+		return nil
+	}
+
+	mechanism := config.AuthMechanism
+	if mechanism == "" {
+		ok, advertised := c.Extension("AUTH")
+		if !ok {
+			return nil
+		}
+		switch {
+		case strings.Contains(advertised, AuthCRAMMD5):
+			mechanism = AuthCRAMMD5
+		case strings.Contains(advertised, AuthPlain):
+			mechanism = AuthPlain
+		default:
+			mechanism = AuthLogin
+		}
+	}
+
+	switch mechanism {
+	case AuthCRAMMD5:
+		return smtp.CRAMMD5Auth(config.Username, config.Password)
+	case AuthLogin:
+		return &loginAuth{username: config.Username, password: config.Password}
+	default:
+		return smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+}
+
+// smtpSender implements gomail.SendCloser on top of net/smtp
+type smtpSender struct {
+	conn   net.Conn
+	client *smtp.Client
+}
+
+// sessionLostError is a server reply after which the session could not be reset, the
+// connection must not be reused
+type sessionLostError struct {
+	reply error
+	cause error
+}
+
+func (e *sessionLostError) Error() string {
+	return e.reply.Error() + " (connection lost: " + e.cause.Error() + ")"
+}
+
+func (e *sessionLostError) Unwrap() error {
+	return e.reply
+}
+
+// abort resets the transaction after a rejected command
+func (s *smtpSender) abort(reply error) error {
+	if err := s.client.Reset(); err != nil {
+		return &sessionLostError{reply: reply, cause: err}
+	}
+	return reply
+}
+
+func (s *smtpSender) Send(from string, to []string, msg io.WriterTo) error {
+	if err := s.client.Mail(from); err != nil {
+		return s.abort(err)
+	}
+
+	for _, addr := range to {
+		if err := s.client.Rcpt(addr); err != nil {
+			return s.abort(err)
+		}
+	}
+
+	w, err := s.client.Data()
+	if err != nil {
+		return s.abort(err)
+	}
+
+	if _, err := msg.WriteTo(w); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Reset sends RSET, the pool uses it to check an idle session is still alive before reusing it
+func (s *smtpSender) Reset() error {
+	s.conn.SetDeadline(time.Now().Add(smtpDialTimeout))
+	defer s.conn.SetDeadline(time.Time{})
+	return s.client.Reset()
+}
+
+// Close says QUIT and closes the connection, Quit leaves it open when the server does not
+// answer so it is closed here either way
+func (s *smtpSender) Close() error {
+	s.conn.SetDeadline(time.Now().Add(smtpDialTimeout))
+	err := s.client.Quit()
+	s.client.Close()
+	return err
+}
+
+// loginAuth implements the LOGIN mechanism which net/smtp does not provide
+type loginAuth struct {
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && server.Name != "localhost" && server.Name != "127.0.0.1" {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("unexpected server challenge: %s", fromServer)
+}
@@ -0,0 +1,324 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"sync"
+	"time"
+
+	"github.com/farhan-nahid/email-service/config"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"gopkg.in/gomail.v2"
+)
+
+// PoolStats is a snapshot of the SMTP pool counters
+type PoolStats struct {
+	Dials      uint64                  `json:"dials"`
+	Reuses     uint64                  `json:"reuses"`
+	Sent       uint64                  `json:"sent"`
+	Failures   uint64                  `json:"failures"`
+	Reconnects uint64                  `json:"reconnects"`
+	Recycled   uint64                  `json:"recycled"`
+	IdleClosed uint64                  `json:"idle_closed"`
+	Stale      uint64                  `json:"stale"`
+	Profiles   map[string]ProfileStats `json:"profiles"`
+}
+
+// ProfileStats holds the connection counts for a single SMTP profile
+type ProfileStats struct {
+	Open int `json:"open"`
+	Idle int `json:"idle"`
+}
+
+// resetter is implemented by senders that can check their session is alive, see smtpSender.Reset
+type resetter interface {
+	Reset() error
+}
+
+type pooledConn struct {
+	sender   gomail.SendCloser
+	messages int
+	lastUsed time.Time
+}
+
+type poolEntry struct {
+	label string
+	open  int
+	idle  []*pooledConn
+}
+
+// SMTPPool keeps persistent SMTP sessions per profile, connections are recycled after
+// MaxMessages sends or when idle for longer than IdleTimeout
+type SMTPPool struct {
+	MaxMessages int
+	MaxIdle     int
+	IdleTimeout time.Duration
+
+	dial    func(SMTPConfig) (gomail.SendCloser, error)
+	mu      sync.Mutex
+	entries map[string]*poolEntry
+	stats   PoolStats
+	done    chan struct{}
+	closed  bool
+}
+
+// NewSMTPPool creates a pool and starts the idle connection reaper
+func NewSMTPPool(maxMessages, maxIdle int, idleTimeout time.Duration) *SMTPPool {
+	p := &SMTPPool{
+		MaxMessages: maxMessages,
+		MaxIdle:     maxIdle,
+		IdleTimeout: idleTimeout,
+		dial:        DialSMTP,
+		entries:     make(map[string]*poolEntry),
+		done:        make(chan struct{}),
+	}
+	go p.reap()
+	return p
+}
+
+// NewSMTPPoolFromConfig creates a pool with the SMTP_POOL_* settings
+func NewSMTPPoolFromConfig(settings config.SMTPConfig) *SMTPPool {
+	return NewSMTPPool(
+		settings.PoolMaxMessages,
+		settings.PoolMaxIdle,
+		time.Duration(settings.PoolIdleTimeoutSeconds)*time.Second,
+	)
+}
+
+// poolKey identifies a profile, the password hash makes credential changes use new connections
+func poolKey(config SMTPConfig) string {
+	return fmt.Sprintf("%s|%d|%s|%s|%s|%x", config.Host, config.Port, config.Username,
+		config.TLSMode, config.AuthMechanism, sha256.Sum256([]byte(config.Password)))
+}
+
+// Envelope is a message together with the SMTP envelope sender and recipients
+type Envelope struct {
+	From    string
+	To      []string
+	Message io.WriterTo
+}
+
+// RawMessage is an already rendered message, it can be written more than once for retries
+type RawMessage []byte
+
+func (r RawMessage) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(r)
+	return int64(n), err
+}
+
+// Send delivers the messages over a pooled connection, reconnecting once when the
+// connection turns out to be broken or the server closes it with a 421 reply
+func (p *SMTPPool) Send(ctx context.Context, config SMTPConfig, msgs ...*Envelope) (err error) {
+	ctx, span := StartSpan(ctx, "smtp.send", semconv.ServerAddress(config.Host), attribute.Int("smtp.messages", len(msgs)))
+	defer func() { EndSpan(span, err) }()
+
+	key := poolKey(config)
+	conn, err := p.get(ctx, key, config)
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range msgs {
+		err = conn.sender.Send(msg.From, msg.To, msg.Message)
+		if err != nil && isBrokenSession(err) {
+			// The session is unusable, replace it and retry the message once
+			p.discard(key, conn)
+			p.count(&p.stats.Reconnects)
+			SMTPRetries.WithLabelValues(config.Host).Inc()
+			span.AddEvent("smtp.reconnect")
+			if conn, err = p.get(ctx, key, config); err != nil {
+				return err
+			}
+			err = conn.sender.Send(msg.From, msg.To, msg.Message)
+		}
+
+		if err != nil {
+			p.count(&p.stats.Failures)
+			if isBrokenSession(err) {
+				p.discard(key, conn)
+				return err
+			}
+			p.put(key, conn)
+			return err
+		}
+
+		conn.messages++
+		p.count(&p.stats.Sent)
+	}
+
+	p.put(key, conn)
+	return nil
+}
+
+// isBrokenSession reports whether the session is unusable after the error. Only server
+// replies leave it usable, except 421 which announces the server is closing the connection
+// and replies that could not be followed by RSET
+func isBrokenSession(err error) bool {
+	var lost *sessionLostError
+	var reply *textproto.Error
+	if errors.As(err, &lost) || !errors.As(err, &reply) {
+		return true
+	}
+	return reply.Code == 421
+}
+
+func (p *SMTPPool) count(counter *uint64) {
+	p.mu.Lock()
+	*counter++
+	p.mu.Unlock()
+}
+
+func (p *SMTPPool) entry(key string, config SMTPConfig) *poolEntry {
+	e, ok := p.entries[key]
+	if !ok {
+		e = &poolEntry{label: fmt.Sprintf("%s@%s:%d", config.Username, config.Host, config.Port)}
+		p.entries[key] = e
+	}
+	return e
+}
+
+func (p *SMTPPool) get(ctx context.Context, key string, config SMTPConfig) (*pooledConn, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, errors.New("smtp pool is closed")
+		}
+
+		e := p.entry(key, config)
+		if len(e.idle) == 0 {
+			p.mu.Unlock()
+			break
+		}
+		conn := e.idle[len(e.idle)-1]
+		e.idle = e.idle[:len(e.idle)-1]
+		if time.Since(conn.lastUsed) >= p.IdleTimeout {
+			e.open--
+			p.stats.IdleClosed++
+			p.mu.Unlock()
+			go conn.sender.Close()
+			continue
+		}
+		p.mu.Unlock()
+
+		// The server may have dropped the session while it was idle
+		if r, ok := conn.sender.(resetter); ok {
+			if err := r.Reset(); err != nil {
+				p.discard(key, conn)
+				p.count(&p.stats.Stale)
+				continue
+			}
+		}
+		p.count(&p.stats.Reuses)
+		return conn, nil
+	}
+
+	_, span := StartSpan(ctx, "smtp.dial", semconv.ServerAddress(config.Host), semconv.ServerPort(config.Port))
+	sender, err := p.dial(config)
+	EndSpan(span, err)
+	if err != nil {
+		p.count(&p.stats.Failures)
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.entry(key, config).open++
+	p.stats.Dials++
+	p.mu.Unlock()
+	return &pooledConn{sender: sender}, nil
+}
+
+func (p *SMTPPool) put(key string, conn *pooledConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e := p.entries[key]
+	if p.closed || conn.messages >= p.MaxMessages || len(e.idle) >= p.MaxIdle {
+		e.open--
+		if conn.messages >= p.MaxMessages {
+			p.stats.Recycled++
+		}
+		go conn.sender.Close()
+		return
+	}
+
+	conn.lastUsed = time.Now()
+	e.idle = append(e.idle, conn)
+}
+
+func (p *SMTPPool) discard(key string, conn *pooledConn) {
+	p.mu.Lock()
+	p.entries[key].open--
+	p.mu.Unlock()
+	go conn.sender.Close()
+}
+
+// reap closes connections idle for longer than IdleTimeout
+func (p *SMTPPool) reap() {
+	ticker := time.NewTicker(p.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			for _, e := range p.entries {
+				kept := e.idle[:0]
+				for _, conn := range e.idle {
+					if time.Since(conn.lastUsed) >= p.IdleTimeout {
+						e.open--
+						p.stats.IdleClosed++
+						go conn.sender.Close()
+						continue
+					}
+					kept = append(kept, conn)
+				}
+				e.idle = kept
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// Stats returns a snapshot of the pool counters
+func (p *SMTPPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := p.stats
+	stats.Profiles = make(map[string]ProfileStats, len(p.entries))
+	for _, e := range p.entries {
+		profile := stats.Profiles[e.label]
+		profile.Open += e.open
+		profile.Idle += len(e.idle)
+		stats.Profiles[e.label] = profile
+	}
+	return stats
+}
+
+// Close stops the reaper and closes every idle connection
+func (p *SMTPPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.done)
+
+	for _, e := range p.entries {
+		for _, conn := range e.idle {
+			e.open--
+			conn.sender.Close()
+		}
+		e.idle = nil
+	}
+}
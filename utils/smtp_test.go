@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSMTPSenderCloseReleasesBrokenSessions(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	defer listener.Close()
+
+	// The server answers EHLO and refuses QUIT, then reports whether the client hung up
+	closed := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			closed <- err
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		reader := bufio.NewReader(conn)
+		io.WriteString(conn, "220 test ESMTP\r\n")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				closed <- err
+				return
+			}
+			switch command := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(command, "EHLO"):
+				io.WriteString(conn, "250 test\r\n")
+			case command == "QUIT":
+				io.WriteString(conn, "421 shutting down\r\n")
+			default:
+				io.WriteString(conn, "250 ok\r\n")
+			}
+		}
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	sender, err := DialSMTP(SMTPConfig{Host: "127.0.0.1", Port: port, TLSMode: TLSModeNone, AuthMechanism: AuthNone})
+	if err != nil {
+		t.Fatalf("dialing: %v", err)
+	}
+
+	if err := sender.Close(); err == nil {
+		t.Fatal("expected the refused QUIT to be reported")
+	}
+	if err := <-closed; err != io.EOF {
+		t.Fatalf("expected the connection to be closed, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"errors"
+	"html/template"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ------------------- Template Registry ------------------- //
+
+// TemplateStatus reports what the registry loaded, used by the readiness check
+type TemplateStatus struct {
+	Loaded   int       `json:"loaded"`
+	LoadedAt time.Time `json:"loaded_at"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// TemplateRegistry parses the templates in a directory once, they are named
+// "<WEBSITE>/<SOURCE>" after their path without the .html extension
+type TemplateRegistry struct {
+	dir       string
+	mu        sync.RWMutex
+	templates map[string]*template.Template
+	status    TemplateStatus
+}
+
+// NewTemplateRegistry creates an empty registry for the directory, call Load to parse it
+func NewTemplateRegistry(dir string) *TemplateRegistry {
+	return &TemplateRegistry{dir: dir, templates: make(map[string]*template.Template)}
+}
+
+// Load parses every .html file of the directory and replaces the loaded templates, the
+// previous templates stay in use when any of them fails to parse
+func (r *TemplateRegistry) Load() error {
+	templates := make(map[string]*template.Template)
+
+	err := filepath.WalkDir(r.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(path) != ".html" {
+			return err
+		}
+
+		t, err := template.ParseFiles(path)
+		if err != nil {
+			return err
+		}
+		templates[r.name(path)] = t
+		return nil
+	})
+	if err == nil && len(templates) == 0 {
+		err = errors.New("no templates found in " + r.dir)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		r.status.Error = err.Error()
+		return err
+	}
+	r.templates = templates
+	r.status = TemplateStatus{Loaded: len(templates), LoadedAt: time.Now()}
+	return nil
+}
+
+// Template returns the template for the website and source, templates added after startup
+// are parsed and cached on first use
+func (r *TemplateRegistry) Template(name string) (*template.Template, error) {
+	r.mu.RLock()
+	t, ok := r.templates[name]
+	r.mu.RUnlock()
+	if ok {
+		return t, nil
+	}
+
+	path := filepath.Join(r.dir, filepath.FromSlash(name)+".html")
+	if !strings.HasPrefix(filepath.Clean(path), filepath.Clean(r.dir)+string(filepath.Separator)) {
+		return nil, errors.New("invalid template name " + name)
+	}
+
+	t, err := template.ParseFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.templates[name] = t
+	r.status.Loaded = len(r.templates)
+	r.mu.Unlock()
+	return t, nil
+}
+
+// Status returns the result of the last load
+func (r *TemplateRegistry) Status() TemplateStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.status
+}
+
+func (r *TemplateRegistry) name(path string) string {
+	relative, _ := filepath.Rel(r.dir, path)
+	return filepath.ToSlash(strings.TrimSuffix(relative, ".html"))
+}
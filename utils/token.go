@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidToken is returned when a token is malformed or its signature does not match
+var ErrInvalidToken = errors.New("invalid token")
+
+func tokenSecret(secret string) ([]byte, error) {
+	if secret == "" {
+		return nil, errors.New("TOKEN_SECRET is not set")
+	}
+	return []byte(secret), nil
+}
+
+func tokenSignature(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignToken encodes the value as JSON and signs it with the TOKEN_SECRET, the result is URL safe
+func SignToken(secret string, value interface{}) (string, error) {
+	key, err := tokenSecret(secret)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + tokenSignature(key, payload), nil
+}
+
+// VerifyToken checks the signature of a token created by SignToken and decodes it into value
+func VerifyToken(secret, token string, value interface{}) error {
+	key, err := tokenSecret(secret)
+	if err != nil {
+		return err
+	}
+
+	payload, signature, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(tokenSignature(key, payload))) {
+		return ErrInvalidToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	if err := json.Unmarshal(data, value); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
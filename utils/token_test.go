@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerifyToken(t *testing.T) {
+	token, err := SignToken("secret", TrackingToken{EmailUUID: "email-uuid", URL: "https://example.com/pricing"})
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+	forged, _ := SignToken("secret", TrackingToken{EmailUUID: "email-uuid", URL: "https://attacker.example.net"})
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	tests := []struct {
+		name   string
+		secret string
+		token  string
+		err    error
+	}{
+		{"valid", "secret", token, nil},
+		{"other secret", "other-secret", token, ErrInvalidToken},
+		{"swapped payload", "secret", forgedPayload + "." + signature, ErrInvalidToken},
+		{"truncated signature", "secret", payload + "." + signature[:len(signature)-1], ErrInvalidToken},
+		{"no signature", "secret", payload, ErrInvalidToken},
+		{"signed garbage", "secret", "bm90IGpzb24." + tokenSignature([]byte("secret"), "bm90IGpzb24"), ErrInvalidToken},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var value TrackingToken
+			err := VerifyToken(test.secret, test.token, &value)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+			if err == nil && (value.EmailUUID != "email-uuid" || value.URL != "https://example.com/pricing") {
+				t.Fatalf("unexpected token value %+v", value)
+			}
+		})
+	}
+}
+
+func TestTokensRequireASecret(t *testing.T) {
+	if _, err := SignToken("", TrackingToken{EmailUUID: "email-uuid"}); err == nil {
+		t.Error("expected signing without TOKEN_SECRET to fail")
+	}
+	if err := VerifyToken("", "payload.signature", &TrackingToken{}); err == nil || errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected a configuration error without TOKEN_SECRET, got %v", err)
+	}
+}
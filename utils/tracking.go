@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/farhan-nahid/email-service/config"
+)
+
+// TransparentGIF is the 1x1 pixel served for open tracking
+var TransparentGIF = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// TrackingToken is the signed payload of tracking URLs
+type TrackingToken struct {
+	EmailUUID string `json:"e"`
+	URL       string `json:"u,omitempty"`
+}
+
+// PublicURL joins the path to PUBLIC_BASE_URL, it is empty when no base URL is configured
+func PublicURL(baseURL, path string) string {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if baseURL == "" {
+		return ""
+	}
+	return baseURL + path
+}
+
+// OpenPixelURL builds the signed tracking pixel URL, it is empty when PUBLIC_BASE_URL is not configured
+func OpenPixelURL(cfg *config.Config, emailUUID string) (string, error) {
+	if PublicURL(cfg.Server.PublicBaseURL, "") == "" {
+		return "", nil
+	}
+
+	token, err := SignToken(cfg.Security.TokenSecret, TrackingToken{EmailUUID: emailUUID})
+	if err != nil {
+		return "", err
+	}
+	return PublicURL(cfg.Server.PublicBaseURL, "/t/o/"+token+".gif"), nil
+}
+
+// ClickTrackingURL builds the signed redirect URL for a link, links that can't be tracked are kept
+func ClickTrackingURL(cfg *config.Config, emailUUID, link string) (string, bool) {
+	if PublicURL(cfg.Server.PublicBaseURL, "") == "" || !IsTrackableLink(cfg.Tracking.ClickAllowedHosts, link) {
+		return "", false
+	}
+
+	token, err := SignToken(cfg.Security.TokenSecret, TrackingToken{EmailUUID: emailUUID, URL: link})
+	if err != nil {
+		return "", false
+	}
+	return PublicURL(cfg.Server.PublicBaseURL, "/t/c/"+token), true
+}
+
+// InjectOpenPixel adds the tracking pixel right before the closing body tag
+func InjectOpenPixel(html, pixelURL string) string {
+	pixel := `<img src="` + pixelURL + `" width="1" height="1" alt="" style="display:none" />`
+
+	if index := strings.LastIndex(strings.ToLower(html), "</body>"); index >= 0 {
+		return html[:index] + pixel + html[index:]
+	}
+	return html + pixel
+}
+
+// HashIP hashes a client IP with the TOKEN_SECRET so opens can be counted as unique without
+// storing the address
+func HashIP(secret, ip string) string {
+	sum := sha256.Sum256([]byte(secret + ip))
+	return hex.EncodeToString(sum[:16])
+}
+
+// anchorPattern matches opening anchor tags and hrefPattern the href attribute inside them
+var (
+	anchorPattern = regexp.MustCompile(`(?i)<a\s[^>]*>`)
+	hrefPattern   = regexp.MustCompile(`(?i)\shref\s*=\s*("[^"]*"|'[^']*')`)
+	noTrackAttr   = regexp.MustCompile(`(?i)\sdata-no-track(\s*=\s*("[^"]*"|'[^']*'|\S+))?`)
+)
+
+// RewriteLinks replaces the href of every anchor with the URL returned by rewrite, anchors
+// with a data-no-track attribute (password reset links and the like) are left alone
+func RewriteLinks(body string, rewrite func(link string) (string, bool)) string {
+	return anchorPattern.ReplaceAllStringFunc(body, func(tag string) string {
+		if noTrackAttr.MatchString(tag) {
+			return noTrackAttr.ReplaceAllString(tag, "")
+		}
+
+		match := hrefPattern.FindStringSubmatchIndex(tag)
+		if match == nil {
+			return tag
+		}
+
+		quoted := tag[match[2]:match[3]]
+		link := html.UnescapeString(quoted[1 : len(quoted)-1])
+		rewritten, ok := rewrite(link)
+		if !ok {
+			return tag
+		}
+
+		return tag[:match[2]] + `"` + html.EscapeString(rewritten) + `"` + tag[match[3]:]
+	})
+}
+
+// IsTrackableLink reports whether clicks on the link may be tracked and redirected, only
+// http(s) links to hosts in CLICK_TRACKING_ALLOWED_HOSTS qualify, "*.example.com" also
+// allows subdomains and an empty list disables click tracking
+func IsTrackableLink(allowedHosts []string, link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "" {
+			continue
+		}
+		if host == allowed || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:])) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/farhan-nahid/email-service/config"
+)
+
+func TestIsTrackableLink(t *testing.T) {
+	allowed := []string{"example.com", " *.Shop.example.org ", ""}
+
+	tests := []struct {
+		link      string
+		trackable bool
+	}{
+		{"https://example.com/pricing", true},
+		{"http://EXAMPLE.com", true},
+		{"https://example.com:8443/pricing", true},
+		{"https://www.example.com/pricing", false},
+		{"https://shop.example.org/cart", false},
+		{"https://eu.shop.example.org/cart", true},
+		{"https://evilshop.example.org/cart", false},
+		{"https://example.com.attacker.net", false},
+		{"https://attacker.net/?next=https://example.com", false},
+		{"https://example.com@attacker.net/", false},
+		{"mailto:support@example.com", false},
+		{"javascript:alert(1)", false},
+		{"//example.com/pricing", false},
+		{"/relative/path", false},
+	}
+
+	for _, test := range tests {
+		if trackable := IsTrackableLink(allowed, test.link); trackable != test.trackable {
+			t.Errorf("%s: expected trackable %v, got %v", test.link, test.trackable, trackable)
+		}
+	}
+
+	if IsTrackableLink(nil, "https://example.com") {
+		t.Error("an empty allowlist must disable click tracking")
+	}
+}
+
+func TestClickTrackingURL(t *testing.T) {
+	cfg := &config.Config{
+		Server:   config.ServerConfig{PublicBaseURL: "https://mail.example.com/"},
+		Security: config.SecurityConfig{TokenSecret: "secret"},
+		Tracking: config.TrackingConfig{ClickAllowedHosts: []string{"example.com"}},
+	}
+
+	tracked, ok := ClickTrackingURL(cfg, "email-uuid", "https://example.com/pricing")
+	if !ok || !strings.HasPrefix(tracked, "https://mail.example.com/t/c/") {
+		t.Fatalf("expected a signed redirect URL, got %q", tracked)
+	}
+	var token TrackingToken
+	if err := VerifyToken("secret", strings.TrimPrefix(tracked, "https://mail.example.com/t/c/"), &token); err != nil {
+		t.Fatalf("verifying the redirect token: %v", err)
+	}
+	if token.EmailUUID != "email-uuid" || token.URL != "https://example.com/pricing" {
+		t.Fatalf("unexpected redirect token %+v", token)
+	}
+
+	if _, ok := ClickTrackingURL(cfg, "email-uuid", "https://attacker.net"); ok {
+		t.Error("links to hosts outside the allowlist must not be tracked")
+	}
+
+	cfg.Server.PublicBaseURL = ""
+	if _, ok := ClickTrackingURL(cfg, "email-uuid", "https://example.com/pricing"); ok {
+		t.Error("links must not be tracked without PUBLIC_BASE_URL")
+	}
+}
+
+func TestRewriteLinks(t *testing.T) {
+	rewrite := func(link string) (string, bool) {
+		if !strings.HasPrefix(link, "https://example.com") {
+			return "", false
+		}
+		return "https://mail.example.com/t/c/" + strings.TrimPrefix(link, "https://example.com/") + "?a=1&b=2", true
+	}
+
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{`<a href="https://example.com/pricing">Pricing</a>`, `<a href="https://mail.example.com/t/c/pricing?a=1&amp;b=2">Pricing</a>`},
+		{`<A class='x' HREF='https://example.com/docs'>Docs</A>`, `<A class='x' HREF="https://mail.example.com/t/c/docs?a=1&amp;b=2">Docs</A>`},
+		{`<a href="https://example.com/reset" data-no-track>Reset</a>`, `<a href="https://example.com/reset">Reset</a>`},
+		{`<a data-no-track="true" href="https://example.com/reset">Reset</a>`, `<a href="https://example.com/reset">Reset</a>`},
+		{`<a href="https://attacker.net">Elsewhere</a>`, `<a href="https://attacker.net">Elsewhere</a>`},
+		{`<a name="top">Top</a>`, `<a name="top">Top</a>`},
+	}
+
+	for _, test := range tests {
+		if rewritten := RewriteLinks(test.body, rewrite); rewritten != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.body, test.expected, rewritten)
+		}
+	}
+}
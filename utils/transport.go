@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"context"
+	"log/slog"
+)
+
+// ------------------- Mail Transport ------------------- //
+
+// Transport delivers rendered envelopes, SMTPPool sends them to the resolved SMTP server and
+// Mailbox keeps them for inspection during development
+type Transport interface {
+	Send(ctx context.Context, config SMTPConfig, envelopes ...*Envelope) error
+	Close()
+}
+
+// Mailer renders emails with its template registry and hands them to its transport, sends
+// are logged to the logger of the app it belongs to
+type Mailer struct {
+	Templates *TemplateRegistry
+	Transport Transport
+	Logger    *slog.Logger
+}
+
+// NewMailer creates a mailer for the registry, transport and logger
+func NewMailer(templates *TemplateRegistry, transport Transport, logger *slog.Logger) *Mailer {
+	return &Mailer{Templates: templates, Transport: transport, Logger: logger}
+}
+
+// Captures reports whether emails are kept by the development mailbox instead of being
+// delivered
+func (mailer *Mailer) Captures() bool {
+	_, ok := mailer.Transport.(*Mailbox)
+	return ok
+}
@@ -1,7 +1,8 @@
 package utils
 
 import (
-	"fmt"
+	"log/slog"
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -19,12 +20,18 @@ func SuccessResponse(c *gin.Context, status int, data interface{}, message strin
 }
 
 
-// Error writes an error response with the given status code and error message
+// Error writes an error response with the given status code and error message, a nil error
+// is reported with the status text
 func ErrorResponse(c *gin.Context, status int, err error) {
+	message := http.StatusText(status)
+	if err != nil {
+		message = err.Error()
+	}
+
 	c.JSON(status, gin.H{
 		"success": false,
 		"message": "Error",
-		"error": err.Error(),
+		"error": message,
 	})
 	c.Abort()
 }
@@ -35,7 +42,7 @@ func ValidatorError(c *gin.Context, status int, errors validator.ValidationError
 	var errorMessage []string
 
 	for _, err := range errors {
-		fmt.Println(err)
+		slog.Debug("validation failed", slog.String("field", err.Namespace()), slog.String("tag", err.ActualTag()))
 		switch err.ActualTag() {
 		case "required":
 			errorMessage = append(errorMessage, err.Field() + " is required")
@@ -49,6 +56,22 @@ func ValidatorError(c *gin.Context, status int, errors validator.ValidationError
 			errorMessage = append(errorMessage, err.Field() + " is not a valid website")
 		case "uuid":
 			errorMessage = append(errorMessage, err.Field() + " is not a valid UUID")
+		case "tls_mode":
+			errorMessage = append(errorMessage, err.Field() + " is not a valid TLS mode")
+		case "auth_mechanism":
+			errorMessage = append(errorMessage, err.Field() + " is not a valid auth mechanism")
+		case "suppression_scope":
+			errorMessage = append(errorMessage, err.Field() + " is not a valid suppression scope")
+		case "suppression_reason":
+			errorMessage = append(errorMessage, err.Field() + " is not a valid suppression reason")
+		case "api_scope":
+			errorMessage = append(errorMessage, err.Field() + " is not a valid scope")
+		case "required_without":
+			errorMessage = append(errorMessage, err.Field() + " is required unless " + err.Param() + " is set")
+		case "excluded_with":
+			errorMessage = append(errorMessage, err.Field() + " cannot be set together with " + err.Param())
+		case "required_if":
+			errorMessage = append(errorMessage, err.Field() + " is required when " + strings.Replace(err.Param(), " ", " is ", 1))
 		default:
 			errorMessage = append(errorMessage, err.Field() + " is not valid")
 		}
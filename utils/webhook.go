@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+)
+
+// ErrUnsafeWebhookURL is returned for webhook URLs that are not https or reach an internal address
+var ErrUnsafeWebhookURL = errors.New("webhook URL must use https and resolve to a public address")
+
+// sharedAddressSpace is carrier-grade NAT, cloud providers serve metadata endpoints from it
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// webhookClient only connects to public addresses, the check runs on the resolved address of
+// every connection so DNS changes and redirects can't reach internal services
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+					return ErrUnsafeWebhookURL
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConnsPerHost: 2,
+	},
+	CheckRedirect: func(request *http.Request, via []*http.Request) error {
+		if request.URL.Scheme != "https" {
+			return ErrUnsafeWebhookURL
+		}
+		if len(via) >= 5 {
+			return errors.New("too many webhook redirects")
+		}
+		return nil
+	},
+}
+
+// isPublicIP reports whether webhooks may be delivered to the address
+func isPublicIP(ip net.IP) bool {
+	if addr, ok := netip.AddrFromSlice(ip); ok && sharedAddressSpace.Contains(addr.Unmap()) {
+		return false
+	}
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// ValidateWebhookURL requires an https URL whose host only resolves to public addresses
+func ValidateWebhookURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return ErrUnsafeWebhookURL
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("resolving webhook host: %w", err)
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return ErrUnsafeWebhookURL
+		}
+	}
+	return nil
+}
+
+// SignWebhook returns the HMAC-SHA256 signature subscribers use to verify a delivery,
+// it covers the timestamp so captured requests can't be replayed later
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DeliverWebhook posts a signed JSON event and returns the response status code, the trace
+// context is sent along so subscribers can continue the trace
+func DeliverWebhook(ctx context.Context, url, secret, eventID string, body []byte) (statusCode int, err error) {
+	ctx, span := StartSpan(ctx, "webhook.deliver", attribute.String("webhook.event_id", eventID))
+	defer func() {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(statusCode))
+		EndSpan(span, err)
+	}()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+	timestamp := time.Now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "email-service-webhooks")
+	request.Header.Set("X-Webhook-ID", eventID)
+	request.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	request.Header.Set("X-Webhook-Signature", SignWebhook(secret, timestamp, body))
+
+	response, err := webhookClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
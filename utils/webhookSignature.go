@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrInvalidSignature is returned when a provider webhook fails verification
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// webhookMaxAge bounds replays of signed provider webhooks
+const webhookMaxAge = 10 * time.Minute
+
+// snsMaxAge is a little longer because SNS keeps the original timestamp when it retries a
+// delivery
+const snsMaxAge = 15 * time.Minute
+
+// ------------------- Amazon SNS ------------------- //
+
+// snsHostPattern only trusts certificates and subscription URLs served by SNS itself
+var snsHostPattern = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// SNSMessage is the envelope SNS posts to HTTP subscribers
+type SNSMessage struct {
+	Type             string `json:"Type"`
+	MessageId        string `json:"MessageId"`
+	Token            string `json:"Token"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+	SubscribeURL     string `json:"SubscribeURL"`
+}
+
+var (
+	snsCertCache   = make(map[string]*x509.Certificate)
+	snsCertCacheMu sync.Mutex
+)
+
+// IsSNSURL reports whether the URL points at an SNS endpoint
+func IsSNSURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && u.Scheme == "https" && snsHostPattern.MatchString(u.Hostname())
+}
+
+func snsCertificate(certURL string) (*x509.Certificate, error) {
+	snsCertCacheMu.Lock()
+	defer snsCertCacheMu.Unlock()
+
+	if cert, ok := snsCertCache[certURL]; ok {
+		return cert, nil
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	response, err := client.Get(certURL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("sns signing certificate is not PEM encoded")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	snsCertCache[certURL] = cert
+	return cert, nil
+}
+
+// VerifySNSMessage checks the SNS signature against the certificate it references, messages
+// signed too long ago are rejected so captured notifications cannot be replayed
+func VerifySNSMessage(message *SNSMessage) error {
+	if !IsSNSURL(message.SigningCertURL) {
+		return ErrInvalidSignature
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, message.Timestamp)
+	if err != nil || time.Since(timestamp).Abs() > snsMaxAge {
+		return ErrInvalidSignature
+	}
+
+	// The string to sign lists the fields alphabetically, see the SNS documentation
+	fields := [][2]string{{"Message", message.Message}, {"MessageId", message.MessageId}}
+	switch message.Type {
+	case "Notification":
+		if message.Subject != "" {
+			fields = append(fields, [2]string{"Subject", message.Subject})
+		}
+		fields = append(fields, [2]string{"Timestamp", message.Timestamp}, [2]string{"TopicArn", message.TopicArn})
+	case "SubscriptionConfirmation", "UnsubscribeConfirmation":
+		fields = append(fields,
+			[2]string{"SubscribeURL", message.SubscribeURL},
+			[2]string{"Timestamp", message.Timestamp},
+			[2]string{"Token", message.Token},
+			[2]string{"TopicArn", message.TopicArn})
+	default:
+		return ErrInvalidSignature
+	}
+	fields = append(fields, [2]string{"Type", message.Type})
+
+	var stringToSign []byte
+	for _, field := range fields {
+		stringToSign = append(stringToSign, field[0]+"\n"+field[1]+"\n"...)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(message.Signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	algorithm := x509.SHA1WithRSA
+	if message.SignatureVersion == "2" {
+		algorithm = x509.SHA256WithRSA
+	}
+
+	cert, err := snsCertificate(message.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	if err := cert.CheckSignature(algorithm, stringToSign, signature); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// ------------------- SendGrid ------------------- //
+
+// VerifySendGridSignature checks the ECDSA signature of the SendGrid event webhook, every
+// request is rejected while no public key is configured
+func VerifySendGridSignature(publicKey, signature, timestamp string, body []byte) error {
+	if publicKey == "" {
+		return ErrInvalidSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(seconds, 0)).Abs() > webhookMaxAge {
+		return ErrInvalidSignature
+	}
+
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return err
+	}
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return errors.New("sendgrid public key is not an ECDSA key")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	digest := sha256.Sum256(append([]byte(timestamp), body...))
+	if !ecdsa.VerifyASN1(ecdsaKey, digest[:], sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// ------------------- Mailgun ------------------- //
+
+// VerifyMailgunSignature checks the HMAC Mailgun adds to every webhook payload. Anyone can
+// compute the HMAC of an empty key, so every request is rejected while no key is configured
+func VerifyMailgunSignature(signingKey, timestamp, token, signature string) error {
+	if signingKey == "" {
+		return ErrInvalidSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(seconds, 0)).Abs() > webhookMaxAge {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(timestamp + token))
+	if !hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// ------------------- Basic Auth ------------------- //
+
+// VerifyBasicAuth compares webhook credentials in constant time, used for providers like
+// Postmark that authenticate webhooks with basic auth instead of signatures. Every request is
+// rejected until both the username and password are configured
+func VerifyBasicAuth(r *http.Request, username, password string) error {
+	user, pass, ok := r.BasicAuth()
+	if !ok || username == "" || password == "" ||
+		subtle.ConstantTimeCompare([]byte(user), []byte(username)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(pass), []byte(password)) != 1 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
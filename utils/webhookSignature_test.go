@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func mailgunSignature(key, timestamp, token string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp + token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyMailgunSignature(t *testing.T) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		key       string
+		timestamp string
+		signature string
+		valid     bool
+	}{
+		{"valid", "signing-key", now, mailgunSignature("signing-key", now, "token"), true},
+		{"wrong key", "signing-key", now, mailgunSignature("other-key", now, "token"), false},
+		{"stale timestamp", "signing-key", stale, mailgunSignature("signing-key", stale, "token"), false},
+		{"invalid timestamp", "signing-key", "yesterday", mailgunSignature("signing-key", "yesterday", "token"), false},
+		// Anyone can compute the HMAC of an empty key
+		{"unset key", "", now, mailgunSignature("", now, "token"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := VerifyMailgunSignature(test.key, test.timestamp, "token", test.signature)
+			if test.valid && err != nil {
+				t.Fatalf("expected a valid signature, got %v", err)
+			}
+			if !test.valid && !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifySendGridSignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := base64.StdEncoding.EncodeToString(der)
+
+	sign := func(timestamp string, body []byte) string {
+		digest := sha256.Sum256(append([]byte(timestamp), body...))
+		signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(signature)
+	}
+
+	body := []byte(`[{"event":"bounce"}]`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	if err := VerifySendGridSignature(publicKey, sign(now, body), now, body); err != nil {
+		t.Fatalf("expected a valid signature, got %v", err)
+	}
+	if err := VerifySendGridSignature(publicKey, sign(now, body), now, []byte(`[{"event":"spamreport"}]`)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected a tampered body to be rejected, got %v", err)
+	}
+	if err := VerifySendGridSignature(publicKey, sign(stale, body), stale, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected a stale timestamp to be rejected, got %v", err)
+	}
+	if err := VerifySendGridSignature("", sign(now, body), now, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected an unset public key to reject every request, got %v", err)
+	}
+}
+
+func TestVerifyBasicAuth(t *testing.T) {
+	tests := []struct {
+		name             string
+		user, password   string
+		configuredUser   string
+		configuredSecret string
+		valid            bool
+	}{
+		{"valid", "postmark", "secret", "postmark", "secret", true},
+		{"wrong password", "postmark", "guess", "postmark", "secret", false},
+		{"wrong user", "other", "secret", "postmark", "secret", false},
+		{"unset user", "", "", "", "secret", false},
+		{"unset password", "postmark", "", "postmark", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("POST", "/webhooks/postmark", nil)
+			request.SetBasicAuth(test.user, test.password)
+
+			err := VerifyBasicAuth(request, test.configuredUser, test.configuredSecret)
+			if test.valid && err != nil {
+				t.Fatalf("expected valid credentials, got %v", err)
+			}
+			if !test.valid && !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+
+	// Requests without credentials are rejected even when none are configured
+	if err := VerifyBasicAuth(httptest.NewRequest("POST", "/webhooks/postmark", nil), "", ""); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected a request without credentials to be rejected, got %v", err)
+	}
+}
+
+func TestVerifySNSMessageRejectsForeignCertificates(t *testing.T) {
+	for _, certURL := range []string{
+		"",
+		"http://sns.us-east-1.amazonaws.com/cert.pem",
+		"https://sns.us-east-1.amazonaws.com.attacker.example/cert.pem",
+		"https://attacker.example/sns.us-east-1.amazonaws.com/cert.pem",
+	} {
+		message := &SNSMessage{Type: "Notification", SigningCertURL: certURL}
+		if err := VerifySNSMessage(message); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("expected %q to be rejected, got %v", certURL, err)
+		}
+	}
+
+	if !IsSNSURL("https://sns.eu-west-1.amazonaws.com/SimpleNotificationService.pem") {
+		t.Error("expected the regional SNS endpoint to be trusted")
+	}
+}
+
+func TestVerifySNSMessageRejectsStaleMessages(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Seed the cache so the test never fetches the certificate
+	const certURL = "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-test.pem"
+	snsCertCacheMu.Lock()
+	snsCertCache[certURL] = cert
+	snsCertCacheMu.Unlock()
+	t.Cleanup(func() {
+		snsCertCacheMu.Lock()
+		delete(snsCertCache, certURL)
+		snsCertCacheMu.Unlock()
+	})
+
+	sign := func(timestamp string) *SNSMessage {
+		message := &SNSMessage{
+			Type:             "Notification",
+			MessageId:        "message-id",
+			TopicArn:         "arn:aws:sns:us-east-1:123456789012:ses-events",
+			Message:          `{"notificationType":"Delivery"}`,
+			Timestamp:        timestamp,
+			SignatureVersion: "2",
+			SigningCertURL:   certURL,
+		}
+		stringToSign := "Message\n" + message.Message + "\nMessageId\n" + message.MessageId +
+			"\nTimestamp\n" + timestamp + "\nTopicArn\n" + message.TopicArn + "\nType\nNotification\n"
+		digest := sha256.Sum256([]byte(stringToSign))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		message.Signature = base64.StdEncoding.EncodeToString(signature)
+		return message
+	}
+
+	tests := []struct {
+		name      string
+		timestamp string
+		valid     bool
+	}{
+		{"valid", time.Now().UTC().Format("2006-01-02T15:04:05.000Z"), true},
+		{"stale timestamp", time.Now().Add(-time.Hour).UTC().Format(time.RFC3339), false},
+		{"future timestamp", time.Now().Add(time.Hour).UTC().Format(time.RFC3339), false},
+		{"invalid timestamp", "yesterday", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := VerifySNSMessage(sign(test.timestamp))
+			if test.valid && err != nil {
+				t.Fatalf("expected a valid message, got %v", err)
+			}
+			if !test.valid && !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		safe bool
+	}{
+		{"https://93.184.215.14/hooks/email", true},
+		{"http://93.184.215.14/hooks/email", false},
+		{"https://127.0.0.1/hooks/email", false},
+		{"https://localhost/hooks/email", false},
+		{"https://10.0.0.8/hooks/email", false},
+		{"https://192.168.1.20/hooks/email", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+		{"https://100.100.100.200/latest/meta-data", false},
+		{"https://[::1]/hooks/email", false},
+		{"https://[fd00::1]/hooks/email", false},
+		{"https://[::ffff:127.0.0.1]/hooks/email", false},
+		{"https:///hooks/email", false},
+	}
+
+	for _, test := range tests {
+		err := ValidateWebhookURL(context.Background(), test.url)
+		if test.safe && err != nil {
+			t.Errorf("expected %s to be accepted, got %v", test.url, err)
+		}
+		if !test.safe && !errors.Is(err, ErrUnsafeWebhookURL) {
+			t.Errorf("expected %s to be rejected, got %v", test.url, err)
+		}
+	}
+}
+
+func TestDeliverWebhookRefusesInternalAddresses(t *testing.T) {
+	delivered := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered = true
+	}))
+	defer server.Close()
+
+	// The address is checked when connecting, a subscription whose host later resolves to an
+	// internal address is refused too
+	_, err := DeliverWebhook(context.Background(), server.URL, "secret", "event", []byte(`{}`))
+	if !errors.Is(err, ErrUnsafeWebhookURL) || delivered {
+		t.Fatalf("expected the delivery to a loopback address to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/farhan-nahid/email-service/models"
+	"github.com/farhan-nahid/email-service/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	webhookPollInterval = 5 * time.Second
+	webhookBatchSize    = 50
+	webhookMaxAttempts  = 8
+	webhookBaseBackoff  = 30 * time.Second
+	webhookMaxBackoff   = 6 * time.Hour
+
+	// webhookLease keeps other replicas from picking up a delivery while it is being sent
+	webhookLease = time.Minute
+)
+
+// StartWebhookDispatcher delivers queued webhook events of the database until the context
+// is cancelled
+func StartWebhookDispatcher(ctx context.Context, db *gorm.DB, logger *slog.Logger) {
+	go func() {
+		ticker := time.NewTicker(webhookPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				dispatchWebhooks(db, logger)
+			}
+		}
+	}()
+}
+
+func dispatchWebhooks(db *gorm.DB, logger *slog.Logger) {
+	var pending int64
+	if err := db.Model(&models.WebhookDelivery{}).Where("state = ?", models.DeliveryPending).Count(&pending).Error; err == nil {
+		utils.WebhookQueueDepth.Set(float64(pending))
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := db.
+		Where("state = ? AND next_attempt_at <= ?", models.DeliveryPending, time.Now()).
+		Order("next_attempt_at").
+		Limit(webhookBatchSize).
+		Find(&deliveries).Error
+	if err != nil {
+		logger.Error("Failed to load webhook deliveries", slog.Any("error", err))
+		return
+	}
+
+	for _, delivery := range deliveries {
+		if claimDelivery(db, &delivery) {
+			deliver(db, logger, &delivery)
+		}
+	}
+}
+
+// claimDelivery pushes the next attempt into the future, only the replica whose update
+// matched the row it loaded sends the delivery
+func claimDelivery(db *gorm.DB, delivery *models.WebhookDelivery) bool {
+	leaseUntil := time.Now().Add(webhookLease)
+	result := db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND next_attempt_at = ?", delivery.ID, delivery.NextAttemptAt).
+		Update("next_attempt_at", leaseUntil)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+
+	delivery.NextAttemptAt = leaseUntil
+	return true
+}
+
+func deliver(db *gorm.DB, logger *slog.Logger, delivery *models.WebhookDelivery) {
+	var subscription models.WebhookSubscription
+	if err := db.First(&subscription, delivery.SubscriptionID).Error; err != nil {
+		// The subscription was deleted, nothing left to deliver to
+		delivery.State = models.DeliveryFailed
+		delivery.LastError = err.Error()
+		db.Save(delivery)
+		return
+	}
+
+	ctx := utils.ExtractTraceContext(context.Background(), delivery.TraceParent)
+	statusCode, err := utils.DeliverWebhook(ctx, subscription.URL, string(subscription.Secret), delivery.EventID.String(), []byte(delivery.Payload))
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+
+	if err == nil {
+		now := time.Now()
+		delivery.State = models.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		utils.WebhookAttempts.WithLabelValues("delivered").Inc()
+	} else {
+		delivery.LastError = err.Error()
+		// Retrying can't help when the subscription points at an internal address
+		if delivery.Attempts >= webhookMaxAttempts || errors.Is(err, utils.ErrUnsafeWebhookURL) {
+			delivery.State = models.DeliveryFailed
+			utils.WebhookAttempts.WithLabelValues("failed").Inc()
+		} else {
+			delivery.NextAttemptAt = time.Now().Add(webhookBackoff(delivery.Attempts))
+			utils.WebhookAttempts.WithLabelValues("retry").Inc()
+		}
+	}
+
+	if err := db.Save(delivery).Error; err != nil {
+		logger.Error("Failed to save webhook delivery", slog.Uint64("delivery_id", uint64(delivery.ID)), slog.Any("error", err))
+	}
+}
+
+// webhookBackoff doubles the delay per attempt with up to 20% jitter
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff << (attempts - 1)
+	if backoff > webhookMaxBackoff || backoff <= 0 {
+		backoff = webhookMaxBackoff
+	}
+	return backoff + time.Duration(rand.Int63n(int64(backoff)/5))
+}